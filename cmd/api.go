@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"redisq/internal/api"
 	"redisq/internal/config"
+	"redisq/internal/infra/tracing"
+	"redisq/internal/usecase"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -11,6 +15,8 @@ import (
 
 func apiCmd() *cobra.Command {
 	var port int
+	var typesFile string
+	var idempotencyWindow time.Duration
 	var command = &cobra.Command{
 		Use:   "api",
 		Short: "Start API server",
@@ -18,11 +24,22 @@ func apiCmd() *cobra.Command {
 			zerolog.SetGlobalLevel(zerolog.InfoLevel)
 			cfg := config.Load()
 			log.Info().Msgf("API server using stream: %s, group: %s", cfg.Redis.StreamKey, cfg.Redis.Group)
-			server := api.NewServer()
+			shutdown, err := tracing.Setup(cmd.Context(), "redisq-api")
+			if err != nil {
+				log.Fatal().Err(err).Msg("failed to set up tracing")
+			}
+			defer func() { _ = shutdown(context.Background()) }()
+
+			server := api.NewServer(api.ServerConfig{
+				TypesFile:         typesFile,
+				IdempotencyWindow: idempotencyWindow,
+			})
 			server.Run(port)
 		},
 	}
 
 	command.Flags().IntVarP(&port, "port", "p", 8080, "Port to run the server on")
+	command.Flags().StringVar(&typesFile, "types-file", "", "JSON file with task type configs")
+	command.Flags().DurationVar(&idempotencyWindow, "idempotency-window", usecase.DefaultIdempotencyWindow, "How long an enqueue idempotency key is remembered")
 	return command
 }
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"redisq/internal/domain"
+
+	"github.com/spf13/cobra"
+)
+
+func cancelCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel <task-id>",
+		Short: "Cancel a queued, delayed or running task",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			cli, err := connect(ctx)
+			if err != nil {
+				return err
+			}
+
+			st, err := cli.Cancel(ctx, args[0])
+			if err != nil {
+				return err
+			}
+
+			switch st {
+			case domain.StatusCancelled:
+				fmt.Println("cancelled")
+			case domain.StatusRunning:
+				fmt.Println("cancellation sent to the worker running the task")
+			default:
+				return fmt.Errorf("task is %s and can no longer be cancelled", st)
+			}
+			return nil
+		},
+	}
+}
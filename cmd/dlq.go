@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"redisq/internal/usecase"
+
+	"github.com/spf13/cobra"
+)
+
+func dlqCmd() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "dlq",
+		Short: "Inspect and manage the dead-letter queue",
+	}
+
+	var limit int64
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List DLQ entries with their failure reason",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			cli, err := connect(ctx)
+			if err != nil {
+				return err
+			}
+
+			entries, _, err := cli.DLQList(ctx, "", limit)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				typ := "?"
+				if e.Task != nil {
+					typ = e.Task.Type
+				}
+				fmt.Printf("%s\t%s\t%s\t%s\t%s\n", e.StreamID, e.At.Format("2006-01-02T15:04:05"), e.TaskID, typ, e.Reason)
+			}
+			return nil
+		},
+	}
+	list.Flags().Int64Var(&limit, "limit", 100, "Max entries to list")
+	command.AddCommand(list)
+
+	command.AddCommand(&cobra.Command{
+		Use:   "inspect <stream-id>",
+		Short: "Print one DLQ entry with its task",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			cli, err := connect(ctx)
+			if err != nil {
+				return err
+			}
+
+			e, err := cli.DLQGet(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			if e == nil {
+				return usecase.ErrDLQEntryNotFound
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(e)
+		},
+	})
+
+	var filter usecase.DLQFilter
+	var all bool
+	requeue := &cobra.Command{
+		Use:   "requeue [stream-id]",
+		Short: "Requeue one DLQ entry, or every entry matching --type/--reason",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !all && filter == (usecase.DLQFilter{}) {
+				return fmt.Errorf("give a stream id, a --type/--reason filter or --all")
+			}
+
+			ctx := context.Background()
+			cli, err := connect(ctx)
+			if err != nil {
+				return err
+			}
+			dl := usecase.DeadLetters{DLQ: cli, Enq: usecase.Enqueuer{Q: cli, Types: usecase.NewTypeRegistry(nil, cli, 0)}}
+
+			if len(args) == 1 {
+				id, err := dl.Requeue(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Printf("requeued as %s\n", id)
+				return nil
+			}
+
+			n, err := dl.RequeueWhere(ctx, filter)
+			fmt.Printf("requeued %d entries\n", n)
+			return err
+		},
+	}
+	requeue.Flags().StringVar(&filter.Type, "type", "", "Only requeue tasks of this type")
+	requeue.Flags().StringVar(&filter.Reason, "reason", "", "Only requeue entries whose reason contains this text")
+	requeue.Flags().BoolVar(&all, "all", false, "Requeue every entry")
+	command.AddCommand(requeue)
+
+	command.AddCommand(&cobra.Command{
+		Use:   "purge",
+		Short: "Delete every DLQ entry",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			cli, err := connect(ctx)
+			if err != nil {
+				return err
+			}
+
+			n, err := cli.DLQPurge(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("purged %d entries\n", n)
+			return nil
+		},
+	})
+
+	return command
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"redisq/internal/usecase"
+
+	"github.com/spf13/cobra"
+)
+
+func periodicCmd() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "periodic",
+		Short: "Manage cron and interval tasks",
+	}
+
+	command.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Print registered periodic tasks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			cli, err := connect(ctx)
+			if err != nil {
+				return err
+			}
+
+			ps, err := cli.Periodics(ctx)
+			if err != nil {
+				return err
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(ps)
+		},
+	})
+
+	command.AddCommand(&cobra.Command{
+		Use:   "import <file>",
+		Short: "Register the periodic tasks from a JSON file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			ps, err := usecase.LoadPeriodicFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			cli, err := connect(ctx)
+			if err != nil {
+				return err
+			}
+
+			for _, p := range ps {
+				if err := cli.SavePeriodic(ctx, p); err != nil {
+					return err
+				}
+				fmt.Printf("registered %s\n", p.Name)
+			}
+			return nil
+		},
+	})
+
+	command.AddCommand(&cobra.Command{
+		Use:   "remove <name>",
+		Short: "Unregister a periodic task",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			cli, err := connect(ctx)
+			if err != nil {
+				return err
+			}
+			return cli.DeletePeriodic(ctx, args[0])
+		},
+	})
+
+	return command
+}
@@ -16,6 +16,10 @@ func Run() {
 
 	command.AddCommand(apiCmd())
 	command.AddCommand(workerCmd())
+	command.AddCommand(typesCmd())
+	command.AddCommand(dlqCmd())
+	command.AddCommand(cancelCmd())
+	command.AddCommand(periodicCmd())
 
 	if err := command.Execute(); err != nil {
 		log.Fatal().Msgf("failed to execute command, err: %v", err.Error())
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"redisq/internal/config"
+	"redisq/internal/infra/redisq"
+	"redisq/internal/usecase"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func typesCmd() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "types",
+		Short: "Manage task type configs stored in Redis",
+	}
+
+	command.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Print stored task type configs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			cli, err := connect(ctx)
+			if err != nil {
+				return err
+			}
+
+			tcs := usecase.NewTypeRegistry(nil, cli, 0).List(ctx)
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(tcs)
+		},
+	})
+
+	command.AddCommand(&cobra.Command{
+		Use:   "import <file>",
+		Short: "Store the task type configs from a JSON file in Redis",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			tcs, err := usecase.LoadTypeFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			cli, err := connect(ctx)
+			if err != nil {
+				return err
+			}
+
+			for _, tc := range tcs {
+				if err := cli.SaveTypeConfig(ctx, tc); err != nil {
+					return err
+				}
+				log.Info().Msgf("stored type config %s", tc.Type)
+			}
+			return nil
+		},
+	})
+
+	return command
+}
+
+// connect opens a Redis client for one-shot CLI commands.
+func connect(ctx context.Context) (*redisq.Client, error) {
+	cli := redisq.New(config.Load().Redis)
+	if err := cli.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return cli, nil
+}
@@ -1,34 +1,178 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"redisq/internal/domain"
+	"redisq/internal/infra/tracing"
+	"redisq/internal/usecase"
 	"redisq/internal/worker"
+	"redisq/pkg/backoff"
 	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
 func workerCmd() *cobra.Command {
 	var (
-		consumerName string
-		baseBackoff  time.Duration
-		maxBackoff   time.Duration
+		consumerName      string
+		baseBackoff       time.Duration
+		maxBackoff        time.Duration
+		typeRetry         map[string]string
+		visibilityTimeout time.Duration
+		reapInterval      time.Duration
+		concurrency       int
+		taskTimeout       time.Duration
+		typeTimeouts      map[string]string
+		shutdownGrace     time.Duration
+		handBack          string
+		typesFile         string
+		periodicFile      string
+		leaderTTL         time.Duration
+		queues            string
+		queueMode         string
+		metricsAddr       string
+		resultTTL         time.Duration
 	)
 
 	var command = &cobra.Command{
 		Use:   "worker",
 		Short: "Start worker server",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			timeouts, err := parseDurations(typeTimeouts)
+			if err != nil {
+				return err
+			}
+
+			policies, err := parseRetryPolicies(typeRetry)
+			if err != nil {
+				return err
+			}
+
+			// both drive tickers, which panic on anything that rounds to zero
+			if leaderTTL < time.Second {
+				return fmt.Errorf("invalid --leader-ttl %s, want at least 1s", leaderTTL)
+			}
+			if reapInterval <= 0 {
+				return fmt.Errorf("invalid --reap-interval %s, want a positive duration", reapInterval)
+			}
+			// zero would reclaim every running task on each reap; running
+			// tasks are touched every third of it, well apart from reaps
+			if visibilityTimeout < 3*reapInterval {
+				return fmt.Errorf("invalid --visibility-timeout %s, want at least 3x --reap-interval (%s)", visibilityTimeout, reapInterval)
+			}
+
+			var hb usecase.HandBack
+			switch handBack {
+			case "requeue":
+				hb = usecase.HandBackRequeue
+			case "pending":
+				hb = usecase.HandBackPending
+			default:
+				return fmt.Errorf("invalid --hand-back %q, want requeue or pending", handBack)
+			}
+
+			qs, err := usecase.ParseQueues(queues)
+			if err != nil {
+				return err
+			}
+			var mode usecase.QueueMode
+			switch queueMode {
+			case "strict":
+				mode = usecase.QueueStrict
+			case "weighted":
+				mode = usecase.QueueWeighted
+			default:
+				return fmt.Errorf("invalid --queue-mode %q, want strict or weighted", queueMode)
+			}
+
+			shutdown, err := tracing.Setup(cmd.Context(), "redisq-worker")
+			if err != nil {
+				return err
+			}
+			defer func() { _ = shutdown(context.Background()) }()
+
 			return worker.Run(worker.WorkerConfig{
-				ConsumerName: consumerName,
-				BaseBackoff:  baseBackoff,
-				MaxBackoff:   maxBackoff,
-			})
+				ConsumerName:      consumerName,
+				BaseBackoff:       baseBackoff,
+				MaxBackoff:        maxBackoff,
+				TypeRetry:         policies,
+				VisibilityTimeout: visibilityTimeout,
+				ReapInterval:      reapInterval,
+				Concurrency:       concurrency,
+				TaskTimeout:       taskTimeout,
+				TypeTimeouts:      timeouts,
+				ShutdownGrace:     shutdownGrace,
+				HandBack:          hb,
+				TypesFile:         typesFile,
+				PeriodicFile:      periodicFile,
+				LeaderTTL:         leaderTTL,
+				Queues:            qs,
+				QueueMode:         mode,
+				MetricsAddr:       metricsAddr,
+				ResultTTL:         resultTTL,
+			}, demoMux())
 		},
 	}
 
 	command.Flags().StringVar(&consumerName, "consumer", "worker-1", "Worker consumer name")
 	command.Flags().DurationVar(&baseBackoff, "base-backoff", 500*time.Millisecond, "Base backoff duration")
 	command.Flags().DurationVar(&maxBackoff, "max-backoff", 30*time.Second, "Max backoff duration")
+	command.Flags().StringToStringVar(&typeRetry, "type-retry", nil, "Per task type retry policy as policy:base[:max], e.g. email.send=linear:1s:1m")
+	command.Flags().DurationVar(&visibilityTimeout, "visibility-timeout", 5*time.Minute, "Idle time after which a pending task is reclaimed")
+	command.Flags().DurationVar(&reapInterval, "reap-interval", 30*time.Second, "How often to scan for stuck tasks")
+	command.Flags().IntVar(&concurrency, "concurrency", 1, "Number of tasks processed at once")
+	command.Flags().DurationVar(&taskTimeout, "task-timeout", 0, "Default per-attempt timeout (0 = none)")
+	command.Flags().StringToStringVar(&typeTimeouts, "type-timeout", nil, "Per task type timeout, e.g. email.send=10s,report.build=5m")
+	command.Flags().DurationVar(&shutdownGrace, "shutdown-grace", 30*time.Second, "How long in-flight tasks may finish after SIGTERM")
+	command.Flags().StringVar(&handBack, "hand-back", "requeue", "What to do with tasks still running after the grace period: requeue or pending")
+	command.Flags().StringVar(&typesFile, "types-file", "", "JSON file with task type configs")
+	command.Flags().StringVar(&periodicFile, "periodic-file", "", "JSON file with periodic task definitions to register")
+	command.Flags().StringVar(&queues, "queues", "default", "Queues to consume, highest priority first, with optional weights, e.g. critical=6,default=3,low=1")
+	command.Flags().StringVar(&queueMode, "queue-mode", "strict", "How to pick between queues: strict (priority order) or weighted (round-robin by weight)")
+	command.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Address to serve Prometheus metrics on, e.g. :9090 (empty = off)")
+	command.Flags().DurationVar(&resultTTL, "result-ttl", 24*time.Hour, "How long task results are kept (0 = don't store)")
+	command.Flags().DurationVar(&leaderTTL, "leader-ttl", 10*time.Second, "Lease lifetime for the leader running the scheduler, reaper and periodic tasks")
 
 	return command
 }
+
+func parseDurations(in map[string]string) (map[string]time.Duration, error) {
+	out := make(map[string]time.Duration, len(in))
+	for k, v := range in {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration for %s: %w", k, err)
+		}
+		out[k] = d
+	}
+	return out, nil
+}
+
+func parseRetryPolicies(in map[string]string) (map[string]backoff.RetryPolicy, error) {
+	out := make(map[string]backoff.RetryPolicy, len(in))
+	for k, v := range in {
+		spec, err := backoff.ParseSpec(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry policy for %s: %w", k, err)
+		}
+		out[k], _ = spec.RetryPolicy()
+	}
+	return out, nil
+}
+
+// demoMux registers the example handlers served by `redisq worker`.
+func demoMux() *usecase.Mux {
+	mux := usecase.NewMux()
+	mux.HandlePrefix("demo.", func(ctx context.Context, t domain.Task) (json.RawMessage, error) {
+		if t.Type == "demo.fail" && t.Attempts < 2 {
+			return nil, errors.New("simulated failure")
+		}
+		log.Ctx(ctx).Info().Msgf("processed task %s type=%s attempts=%d", t.ID, t.Type, t.Attempts)
+		return domain.NewPayload(map[string]any{"echo": t.Payload, "attempts": t.Attempts + 1})
+	})
+	return mux
+}
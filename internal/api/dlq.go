@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"redisq/internal/infra/redisq"
+	"redisq/internal/usecase"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func dlqRoutes(r chi.Router, dl usecase.DeadLetters) {
+	r.Get("/dlq", func(w http.ResponseWriter, r *http.Request) {
+		limit := int64(50)
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 || n > 500 {
+				http.Error(w, "invalid limit", 400)
+				return
+			}
+			limit = n
+		}
+
+		entries, next, err := dl.DLQ.DLQList(r.Context(), r.URL.Query().Get("cursor"), limit)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"entries": entries, "next_cursor": next})
+	})
+
+	r.Get("/dlq/{id}", func(w http.ResponseWriter, r *http.Request) {
+		e, err := dl.DLQ.DLQGet(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if e == nil {
+			http.Error(w, usecase.ErrDLQEntryNotFound.Error(), 404)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(e)
+	})
+
+	r.Post("/dlq/{id}/requeue", func(w http.ResponseWriter, r *http.Request) {
+		id, err := dl.Requeue(r.Context(), chi.URLParam(r, "id"))
+		if errors.Is(err, usecase.ErrDLQEntryNotFound) {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+		if errors.Is(err, redisq.ErrUniqueConflict) {
+			http.Error(w, err.Error(), 409)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": id})
+	})
+
+	// requeue every entry matching ?type= and/or ?reason= (all if neither)
+	r.Post("/dlq/requeue", func(w http.ResponseWriter, r *http.Request) {
+		f := usecase.DLQFilter{Type: r.URL.Query().Get("type"), Reason: r.URL.Query().Get("reason")}
+		n, err := dl.RequeueWhere(r.Context(), f)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"requeued": n})
+	})
+
+	r.Delete("/dlq", func(w http.ResponseWriter, r *http.Request) {
+		n, err := dl.DLQ.DLQPurge(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"purged": n})
+	})
+}
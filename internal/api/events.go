@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ssePing keeps idle event streams open through proxies.
+const ssePing = 15 * time.Second
+
+// eventRoutes streams task lifecycle events as server-sent events. Streams
+// end when the client goes away or stop is closed on shutdown.
+func eventRoutes(r chi.Router, q ports.Queue, events ports.EventStream, stop <-chan struct{}) {
+	// one task: its current state first, then every transition until it
+	// reaches a final state
+	r.Get("/tasks/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		t, err := q.Get(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if t == nil {
+			http.Error(w, "task not found", 404)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		ch, err := events.Events(ctx, t.Type)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		// read again now that we're subscribed, so nothing falls in between
+		if t, err = q.Get(ctx, id); err != nil || t == nil {
+			http.Error(w, "failed to read task", 500)
+			return
+		}
+
+		sse := startSSE(w)
+		current := domain.NewTaskEvent(*t, string(t.Status))
+		if sse.send(current) != nil || current.Final() {
+			return
+		}
+		sse.stream(ctx, ch, stop, func(e domain.TaskEvent) (bool, bool) {
+			return e.TaskID == id, e.Final()
+		})
+	})
+
+	// every task, or every task of ?type=
+	r.Get("/events", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		ch, err := events.Events(ctx, r.URL.Query().Get("type"))
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		sse := startSSE(w)
+		sse.stream(ctx, ch, stop, func(domain.TaskEvent) (bool, bool) { return true, false })
+	})
+}
+
+type sseWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func startSSE(w http.ResponseWriter) sseWriter {
+	rc := http.NewResponseController(w)
+	// streams outlive the server's WriteTimeout
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+	return sseWriter{w: w, rc: rc}
+}
+
+func (s sseWriter) send(e domain.TaskEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", e.Event, b); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// stream forwards the events match accepts until ctx is done, stop is
+// closed, a write fails or match reports the last one.
+func (s sseWriter) stream(ctx context.Context, ch <-chan domain.TaskEvent, stop <-chan struct{}, match func(domain.TaskEvent) (ok, last bool)) {
+	ping := time.NewTicker(ssePing)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil || s.rc.Flush() != nil {
+				return
+			}
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			send, last := match(e)
+			if !send {
+				continue
+			}
+			if s.send(e) != nil || last {
+				return
+			}
+		}
+	}
+}
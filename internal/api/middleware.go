@@ -11,9 +11,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Middleware func(http.Handler) http.Handler
@@ -48,6 +54,12 @@ func (rw *responseWriter) WriteHeader(code int) {
 	}
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush event streams.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func logSeverity(statusCode int) zerolog.Level {
 	switch {
 	case statusCode >= 500:
@@ -244,3 +256,48 @@ func corsHandler(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// tracingHandler starts a server span per request, continuing the caller's
+// trace if it sent a traceparent header, and adds its trace ID to the
+// logger. It runs inside the chi router so the span can be named after the
+// matched route.
+func tracingHandler(filter func(w http.ResponseWriter, r *http.Request) bool) func(http.Handler) http.Handler {
+	tracer := otel.Tracer("redisq/internal/api")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if filter != nil && filter(w, r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("http.request_id", r.Header.Get("X-Request-Id")),
+				),
+			)
+			defer span.End()
+			// handler log lines carry the ID the enqueued task's logs will share
+			ctx = log.Ctx(ctx).With().Str("trace_id", span.SpanContext().TraceID().String()).Logger().WithContext(ctx)
+
+			ww := wrapResponseWriter(w)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(r.Method + " " + rctx.RoutePattern())
+				span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"redisq/internal/usecase"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func periodicRoutes(r chi.Router, store ports.PeriodicStore) {
+	r.Get("/periodic", func(w http.ResponseWriter, r *http.Request) {
+		ps, err := store.Periodics(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		sort.Slice(ps, func(i, j int) bool { return ps[i].Name < ps[j].Name })
+		_ = json.NewEncoder(w).Encode(ps)
+	})
+
+	r.Put("/periodic/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var p domain.Periodic
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		p.Name = chi.URLParam(r, "name")
+		if err := usecase.ValidatePeriodic(&p); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+
+		if err := store.SavePeriodic(r.Context(), p); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(p)
+	})
+
+	r.Delete("/periodic/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if err := store.DeletePeriodic(r.Context(), chi.URLParam(r, "name")); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -3,14 +3,19 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"redisq/internal/config"
 	"redisq/internal/domain"
+	"redisq/internal/infra/metrics"
 	"redisq/internal/infra/redisq"
+	"redisq/internal/ports"
 	"redisq/internal/usecase"
+	"redisq/pkg/backoff"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -19,13 +24,35 @@ import (
 )
 
 type enqueueReq struct {
-	Type        string            `json:"type"`
-	Payload     map[string]string `json:"payload"`
-	MaxAttempts int               `json:"max_attempts"`
-	RunAt       *int64            `json:"run_at_ms"` // optional delayed
+	Type        string          `json:"type"`
+	Queue       string          `json:"queue"` // optional named queue
+	Payload     json.RawMessage `json:"payload"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       *int64          `json:"run_at_ms"`   // optional delayed
+	TimeoutMs   int64           `json:"timeout_ms"`  // optional per-attempt timeout
+	DeadlineMs  *int64          `json:"deadline_ms"` // optional absolute deadline
+	Retry       *backoff.Spec   `json:"retry"`       // optional retry policy override
+	// optional, rejects the task while another with the same type and
+	// values for unique.fields is pending
+	Unique *domain.UniqueSpec `json:"unique"`
+	// optional, same as the Idempotency-Key header
+	IdempotencyKey string `json:"idempotency_key"`
 }
 
-func NewServer() *Server {
+// maxIdempotencyKey bounds client-chosen keys, which end up in Redis key names.
+const maxIdempotencyKey = 255
+
+// maxWait caps ?wait= on /enqueue, keeping it under the server's WriteTimeout.
+const maxWait = 50 * time.Second
+
+type ServerConfig struct {
+	// TypesFile optionally seeds the task type registry (JSON array).
+	TypesFile string
+	// IdempotencyWindow is how long an idempotency key is remembered.
+	IdempotencyWindow time.Duration
+}
+
+func NewServer(sc ServerConfig) *Server {
 	ctx := context.Background()
 	cfg := config.Load()
 
@@ -35,37 +62,261 @@ func NewServer() *Server {
 		log.Ctx(ctx).Fatal().Msgf("something went wrong: %s", err)
 	}
 
-	enq := usecase.Enqueuer{Q: cli}
+	var static []domain.TypeConfig
+	if sc.TypesFile != "" {
+		var err error
+		if static, err = usecase.LoadTypeFile(sc.TypesFile); err != nil {
+			log.Fatal().Err(err).Msg("failed to load task types")
+		}
+	}
+	types := usecase.NewTypeRegistry(static, cli, 30*time.Second)
+
+	m := metrics.New(cli)
+	enq := usecase.Enqueuer{Q: cli, Types: types, Metrics: m, IdempotencyWindow: sc.IdempotencyWindow, Results: cli}
 	r := chi.NewRouter()
+	r.Use(tracingHandler(func(w http.ResponseWriter, r *http.Request) bool { return r.URL.Path == "/metrics" }))
+	r.Handle("/metrics", m.Handler())
 	r.Post("/enqueue", func(w http.ResponseWriter, r *http.Request) {
 		var req enqueueReq
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), 400)
 			return
 		}
-		t := domain.Task{Type: req.Type, Payload: req.Payload, MaxAttempts: req.MaxAttempts}
+		t := domain.Task{
+			Type:        req.Type,
+			Queue:       req.Queue,
+			Payload:     req.Payload,
+			MaxAttempts: req.MaxAttempts,
+			Timeout:     time.Duration(req.TimeoutMs) * time.Millisecond,
+			Retry:       req.Retry,
+			Unique:      req.Unique,
+		}
+		if req.Retry != nil {
+			if _, err := req.Retry.RetryPolicy(); err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+		}
+		if req.DeadlineMs != nil {
+			t.Deadline = time.UnixMilli(*req.DeadlineMs)
+		}
+		if req.TimeoutMs < 0 {
+			http.Error(w, "negative timeout_ms", 400)
+			return
+		}
+		if req.Unique != nil && req.Unique.TTLMs < 0 {
+			http.Error(w, "negative unique.ttl_ms", 400)
+			return
+		}
+
+		key := r.Header.Get("Idempotency-Key")
+		if key != "" && req.IdempotencyKey != "" && key != req.IdempotencyKey {
+			http.Error(w, "Idempotency-Key header and idempotency_key differ", 400)
+			return
+		}
+		if key == "" {
+			key = req.IdempotencyKey
+		}
+		if len(key) > maxIdempotencyKey {
+			http.Error(w, fmt.Sprintf("idempotency key longer than %d bytes", maxIdempotencyKey), 400)
+			return
+		}
+
+		var runAt time.Time
+		if req.RunAt != nil {
+			runAt = time.UnixMilli(*req.RunAt)
+		}
+
+		// ?wait=10s blocks until the task finishes and returns its result
+		var wait time.Duration
+		if v := r.URL.Query().Get("wait"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 || d > maxWait {
+				http.Error(w, fmt.Sprintf("wait must be a positive duration up to %s", maxWait), 400)
+				return
+			}
+			wait = d
+		}
 
 		var id string
+		var dup bool
 		var err error
 
-		if req.RunAt != nil {
-			id, err = enq.At(r.Context(), t, time.UnixMilli(*req.RunAt))
-		} else {
+		switch {
+		case key != "":
+			id, dup, err = enq.Once(r.Context(), key, t, runAt)
+		case req.RunAt != nil:
+			id, err = enq.At(r.Context(), t, runAt)
+		default:
 			id, err = enq.Now(r.Context(), t)
 		}
 
+		switch {
+		case errors.Is(err, redisq.ErrUniqueConflict):
+			http.Error(w, err.Error(), 409)
+			return
+		case errors.Is(err, usecase.ErrUniquePayload):
+			http.Error(w, err.Error(), 400)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		resp := map[string]any{"id": id}
+		if dup {
+			resp["duplicate"] = true
+		}
+		if wait == 0 {
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+
+		res, err := enq.Wait(r.Context(), id, wait)
+		switch {
+		case errors.Is(err, usecase.ErrWaitTimeout):
+			// still pending; the caller can poll /tasks/{id}/result
+			if t, err := cli.Get(r.Context(), id); err == nil && t != nil {
+				resp["status"] = t.Status
+			}
+			w.WriteHeader(http.StatusAccepted)
+		case err != nil:
+			http.Error(w, err.Error(), 500)
+			return
+		default:
+			resp["result"] = res
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	r.Get("/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		t, err := cli.Get(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if t == nil {
+			http.Error(w, "task not found", 404)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(t)
+	})
+
+	// the stored outcome of a finished task; 202 while it is still pending
+	r.Get("/tasks/{id}/result", func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		res, err := cli.Result(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if res != nil {
+			_ = json.NewEncoder(w).Encode(res)
+			return
+		}
+
+		t, err := cli.Get(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		switch {
+		case t == nil:
+			http.Error(w, "task not found", 404)
+		case t.Status == domain.StatusQueued || t.Status == domain.StatusDelayed || t.Status == domain.StatusRunning:
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": t.Status})
+		default:
+			http.Error(w, "no result stored for this task, or it expired", 404)
+		}
+	})
+
+	r.Delete("/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		st, err := cli.Cancel(r.Context(), chi.URLParam(r, "id"))
+		if errors.Is(err, redisq.ErrTaskNotFound) {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		switch st {
+		case domain.StatusCancelled:
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": st})
+		case domain.StatusRunning:
+			// the worker running it has been signalled
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": "cancelling"})
+		default:
+			http.Error(w, fmt.Sprintf("task is %s and can no longer be cancelled", st), 409)
+		}
+	})
+
+	r.Get("/tasks", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := ports.TaskFilter{
+			Status: domain.TaskStatus(q.Get("status")),
+			Type:   q.Get("type"),
+		}
+		var err error
+		if v := q.Get("offset"); v != "" {
+			if f.Offset, err = strconv.ParseInt(v, 10, 64); err != nil || f.Offset < 0 {
+				http.Error(w, "invalid offset", 400)
+				return
+			}
+		}
+		if v := q.Get("limit"); v != "" {
+			if f.Limit, err = strconv.ParseInt(v, 10, 64); err != nil || f.Limit <= 0 || f.Limit > 500 {
+				http.Error(w, "invalid limit", 400)
+				return
+			}
+		}
+		if f.Status == "" && f.Type == "" {
+			http.Error(w, "status or type is required", 400)
+			return
+		}
+
+		tasks, total, err := cli.List(r.Context(), f)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
 		}
-		_ = json.NewEncoder(w).Encode(map[string]any{"id": id})
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"tasks":  tasks,
+			"total":  total,
+			"offset": f.Offset,
+		})
+	})
+
+	dlqRoutes(r, usecase.DeadLetters{DLQ: cli, Enq: enq})
+	periodicRoutes(r, cli)
+
+	// closed on shutdown, ending event streams that would otherwise hold it up
+	stop := make(chan struct{})
+	eventRoutes(r, cli, cli, stop)
+
+	r.Get("/types", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(types.List(r.Context()))
+	})
+
+	r.Get("/types/{type}", func(w http.ResponseWriter, r *http.Request) {
+		typ := chi.URLParam(r, "type")
+		for _, tc := range types.List(r.Context()) {
+			if tc.Type == typ {
+				_ = json.NewEncoder(w).Encode(tc)
+				return
+			}
+		}
+		http.Error(w, "unknown task type", 404)
 	})
 
-	return &Server{router: r}
+	return &Server{router: r, stop: stop}
 }
 
 type Server struct {
 	router *chi.Mux
+	stop   chan struct{}
 }
 
 // Run method of the Server struct runs the HTTP server on the specified port. It initializes
@@ -88,6 +339,7 @@ func (s *Server) Run(port int) {
 		ReadTimeout:  60 * time.Second,
 		WriteTimeout: 60 * time.Second,
 	}
+	httpServer.RegisterOnShutdown(func() { close(s.stop) })
 
 	done := make(chan bool)
 	quit := make(chan os.Signal, 1)
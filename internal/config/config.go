@@ -4,6 +4,7 @@ import (
 	"github.com/caarlos0/env/v11"
 
 	"log"
+	"time"
 )
 
 type Config struct {
@@ -18,6 +19,15 @@ type Redis struct {
 	Group         string `env:"Redis_Group"`
 	ScheduledZSet string `env:"Redis_ScheduledZSet"`
 	DLQStreamKey  string `env:"Redis_DLQStreamKey"`
+	TypesKey      string `env:"Redis_TypesKey" envDefault:"redisq:types"`
+	PeriodicKey   string `env:"Redis_PeriodicKey" envDefault:"redisq:periodic"`
+	LeaderKey     string `env:"Redis_LeaderKey" envDefault:"redisq:leader"`
+	// Queues lists named queues besides the default one; each is a stream
+	// of its own (StreamKey:<queue>).
+	Queues []string `env:"Redis_Queues" envSeparator:","`
+	// ResultTTL is how long Cancel keeps the result of a task cancelled
+	// before it ran; zero stores none. Workers use their --result-ttl.
+	ResultTTL time.Duration `env:"Redis_ResultTTL" envDefault:"24h"`
 }
 
 func Load() *Config {
@@ -0,0 +1,30 @@
+package domain
+
+import "encoding/json"
+
+// What a periodic task does about occurrences missed while no worker was up.
+const (
+	// CatchUpSkip drops missed occurrences; only ones still inside the
+	// scheduler's misfire window run.
+	CatchUpSkip = "skip"
+	// CatchUpLatest runs the most recent missed occurrence once.
+	CatchUpLatest = "latest"
+	// CatchUpAll runs every missed occurrence (up to a cap).
+	CatchUpAll = "all"
+)
+
+// Periodic describes a task enqueued on a cron expression or fixed interval.
+// Exactly one of Cron and EveryMs is set.
+type Periodic struct {
+	Name    string `json:"name"`
+	Cron    string `json:"cron,omitempty"`
+	EveryMs int64  `json:"every_ms,omitempty"`
+	CatchUp string `json:"catch_up,omitempty"`
+
+	// template for the tasks it enqueues
+	Type        string          `json:"type"`
+	Queue       string          `json:"queue,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	MaxAttempts int             `json:"max_attempts,omitempty"`
+	TimeoutMs   int64           `json:"timeout_ms,omitempty"`
+}
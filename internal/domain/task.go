@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"redisq/pkg/backoff"
+	"time"
+)
 
 type TaskStatus string
 
@@ -10,15 +14,147 @@ const (
 	StatusDone    TaskStatus = "done"
 	StatusFailed  TaskStatus = "failed"
 	StatusDelayed TaskStatus = "delayed"
+	// StatusDiscarded marks a task its handler chose to drop.
+	StatusDiscarded TaskStatus = "discarded"
+	// StatusCancelled marks a task cancelled through the API or CLI.
+	StatusCancelled TaskStatus = "cancelled"
 )
 
+// Final reports whether a task in status s is finished for good: nothing
+// may run or requeue it again (short of a DLQ requeue).
+func (s TaskStatus) Final() bool {
+	switch s {
+	case StatusDone, StatusFailed, StatusDiscarded, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// DefaultQueue is the queue of tasks enqueued without one.
+const DefaultQueue = "default"
+
+// Statuses lists every TaskStatus.
+var Statuses = []TaskStatus{
+	StatusQueued,
+	StatusRunning,
+	StatusDone,
+	StatusFailed,
+	StatusDelayed,
+	StatusDiscarded,
+	StatusCancelled,
+}
+
 type Task struct {
-	ID          string            `json:"id"`
-	Type        string            `json:"type"`
-	Payload     map[string]string `json:"payload"`
-	Attempts    int               `json:"attempts"`
-	MaxAttempts int               `json:"max_attempts"`
-	Status      TaskStatus        `json:"status"`
-	CreatedAt   time.Time         `json:"created_at"`
-	NextRunAt   time.Time         `json:"next_run_at"`
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Queue       string          `json:"queue,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	Status      TaskStatus      `json:"status"`
+	CreatedAt   time.Time       `json:"created_at"`
+	NextRunAt   time.Time       `json:"next_run_at"`
+	// Timeout bounds a single attempt, Deadline bounds the task as a whole.
+	// Zero values mean no limit.
+	Timeout  time.Duration `json:"timeout,omitempty"`
+	Deadline time.Time     `json:"deadline,omitzero"`
+	// Retry overrides the retry policy for this task only.
+	Retry *backoff.Spec `json:"retry,omitempty"`
+	// LastError holds the most recent failure (or discard/DLQ) reason.
+	LastError string `json:"last_error,omitempty"`
+	// History keeps earlier runs of the task, e.g. the failure that sent it
+	// to the DLQ before it was requeued.
+	History []HistoryEntry `json:"history,omitempty"`
+	// Trace carries the W3C trace context (traceparent, tracestate) the
+	// task was enqueued under, so its attempts join the producer's trace.
+	Trace map[string]string `json:"trace,omitempty"`
+	// Unique keeps a second task with the same UniqueKey, derived from the
+	// type and the Unique.Fields of the payload, from being enqueued while
+	// this one is pending.
+	Unique    *UniqueSpec `json:"unique,omitempty"`
+	UniqueKey string      `json:"unique_key,omitempty"`
+	// CancelRequested is set when the task was cancelled while running, so
+	// whoever picks it up next (another worker, the reaper) cancels it
+	// instead of running it again. Once set it stays set.
+	CancelRequested bool `json:"cancel_requested,omitempty"`
+	// HandedBack is set on a task a worker left pending for the reaper as it
+	// shut down, so the reaper requeues it without counting an attempt.
+	HandedBack bool `json:"handed_back,omitempty"`
+}
+
+// TaskResult is the outcome of a finished task: the handler's result when it
+// succeeded, the last error when it was dead-lettered or discarded.
+type TaskResult struct {
+	TaskID     string          `json:"task_id"`
+	Status     TaskStatus      `json:"status"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Attempts   int             `json:"attempts"`
+	FinishedAt time.Time       `json:"finished_at"`
+}
+
+// EventRetrying is published when a failed attempt is scheduled for another
+// try; a delayed event follows.
+const EventRetrying = "retrying"
+
+// TaskEvent is one lifecycle transition of a task, published as the task's
+// state is saved.
+type TaskEvent struct {
+	// Event is the status the task moved to, or EventRetrying.
+	Event    string     `json:"event"`
+	TaskID   string     `json:"task_id"`
+	Type     string     `json:"type"`
+	Queue    string     `json:"queue,omitempty"`
+	Status   TaskStatus `json:"status"`
+	Attempts int        `json:"attempts"`
+	Error    string     `json:"error,omitempty"`
+	At       time.Time  `json:"at"`
+}
+
+// NewTaskEvent describes t, as just saved, moving on with event.
+func NewTaskEvent(t Task, event string) TaskEvent {
+	e := TaskEvent{
+		Event:    event,
+		TaskID:   t.ID,
+		Type:     t.Type,
+		Queue:    t.Queue,
+		Status:   t.Status,
+		Attempts: t.Attempts,
+		At:       time.Now(),
+	}
+	if event == EventRetrying || e.Final() && e.Status != StatusDone {
+		e.Error = t.LastError
+	}
+	return e
+}
+
+// Final reports whether no further events follow for the task.
+func (e TaskEvent) Final() bool { return e.Status.Final() }
+
+// UniqueSpec makes a task unique while queued, delayed or running.
+type UniqueSpec struct {
+	// Fields are top-level payload fields; none means one task per type.
+	Fields []string `json:"fields,omitempty"`
+	// TTLMs caps how long the lock is held should it never be released.
+	TTLMs int64 `json:"ttl_ms,omitempty"`
+}
+
+type HistoryEntry struct {
+	At       time.Time  `json:"at"`
+	Status   TaskStatus `json:"status"`
+	Attempts int        `json:"attempts"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// NewPayload marshals v into a task payload.
+func NewPayload(v any) (json.RawMessage, error) {
+	return json.Marshal(v)
+}
+
+// Bind unmarshals the task payload into v.
+func (t Task) Bind(v any) error {
+	if len(t.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(t.Payload, v)
 }
@@ -0,0 +1,22 @@
+package domain
+
+import "redisq/pkg/backoff"
+
+// What happens to a task of a type once it runs out of attempts.
+const (
+	OnExhaustedDLQ     = "dlq"
+	OnExhaustedDiscard = "discard"
+)
+
+// TypeConfig is the contract for one task type. Zero fields fall back to the
+// task's own settings or the worker defaults.
+type TypeConfig struct {
+	Type        string        `json:"type"`
+	Queue       string        `json:"queue,omitempty"`
+	MaxAttempts int           `json:"max_attempts,omitempty"`
+	TimeoutMs   int64         `json:"timeout_ms,omitempty"`
+	Retry       *backoff.Spec `json:"retry,omitempty"`
+	Unique      *UniqueSpec   `json:"unique,omitempty"`
+	// OnExhausted is OnExhaustedDLQ (default) or OnExhaustedDiscard.
+	OnExhausted string `json:"on_exhausted,omitempty"`
+}
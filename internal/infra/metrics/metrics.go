@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+var _ ports.Metrics = (*Metrics)(nil)
+
+const namespace = "redisq"
+
+// Metrics exports task counters and handler latencies recorded by this
+// process, plus queue depths read from Redis on every scrape. Each instance
+// has its own registry, so API and worker can run in one process.
+type Metrics struct {
+	reg *prometheus.Registry
+
+	enqueued     *prometheus.CounterVec
+	processed    *prometheus.CounterVec
+	failed       *prometheus.CounterVec
+	retried      *prometheus.CounterVec
+	deadLettered *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+}
+
+// New registers the metrics. stats may be nil to leave out queue depths.
+func New(stats ports.StatsReader) *Metrics {
+	labels := []string{"type", "queue"}
+	counter := func(name, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		}, labels)
+	}
+
+	m := &Metrics{
+		reg:          prometheus.NewRegistry(),
+		enqueued:     counter("tasks_enqueued_total", "Tasks enqueued, immediate or delayed."),
+		processed:    counter("tasks_processed_total", "Handler attempts that succeeded."),
+		failed:       counter("tasks_failed_total", "Handler attempts that failed."),
+		retried:      counter("tasks_retried_total", "Failed tasks scheduled for another attempt."),
+		deadLettered: counter("tasks_dead_lettered_total", "Tasks moved to the DLQ."),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "handler_duration_seconds",
+			Help:      "Handler attempt latency.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 4, 9), // 5ms .. ~5.5m
+		}, labels),
+	}
+
+	m.reg.MustRegister(
+		m.enqueued, m.processed, m.failed, m.retried, m.deadLettered, m.duration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if stats != nil {
+		m.reg.MustRegister(&statsCollector{stats: stats})
+	}
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.reg, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) Enqueued(t domain.Task) {
+	m.enqueued.WithLabelValues(t.Type, queue(t)).Inc()
+}
+
+func (m *Metrics) Attempted(t domain.Task, d time.Duration, err error) {
+	m.duration.WithLabelValues(t.Type, queue(t)).Observe(d.Seconds())
+	if err != nil {
+		m.failed.WithLabelValues(t.Type, queue(t)).Inc()
+		return
+	}
+	m.processed.WithLabelValues(t.Type, queue(t)).Inc()
+}
+
+func (m *Metrics) Retried(t domain.Task) {
+	m.retried.WithLabelValues(t.Type, queue(t)).Inc()
+}
+
+func (m *Metrics) DeadLettered(t domain.Task) {
+	m.deadLettered.WithLabelValues(t.Type, queue(t)).Inc()
+}
+
+func queue(t domain.Task) string {
+	if t.Queue == "" {
+		return domain.DefaultQueue
+	}
+	return t.Queue
+}
+
+var (
+	streamLengthDesc = prometheus.NewDesc(namespace+"_stream_length", "Entries in the queue's stream.", []string{"queue"}, nil)
+	pendingDesc      = prometheus.NewDesc(namespace+"_stream_pending", "Entries delivered to a consumer but not acked.", []string{"queue"}, nil)
+	lagDesc          = prometheus.NewDesc(namespace+"_consumer_lag", "Entries not yet delivered to the consumer group.", []string{"queue"}, nil)
+	scheduledDesc    = prometheus.NewDesc(namespace+"_scheduled_tasks", "Delayed tasks waiting in the scheduled ZSET.", nil, nil)
+	dlqLengthDesc    = prometheus.NewDesc(namespace+"_dlq_length", "Entries in the DLQ stream.", nil, nil)
+)
+
+// statsCollector reads queue depths from Redis at scrape time, so every
+// instance reports the same numbers without keeping any state.
+type statsCollector struct {
+	stats ports.StatsReader
+}
+
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- streamLengthDesc
+	ch <- pendingDesc
+	ch <- lagDesc
+	ch <- scheduledDesc
+	ch <- dlqLengthDesc
+}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s, err := c.stats.Stats(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to read queue stats")
+		return
+	}
+
+	for _, q := range s.Queues {
+		ch <- prometheus.MustNewConstMetric(streamLengthDesc, prometheus.GaugeValue, float64(q.Length), q.Queue)
+		ch <- prometheus.MustNewConstMetric(pendingDesc, prometheus.GaugeValue, float64(q.Pending), q.Queue)
+		if q.Lag >= 0 {
+			ch <- prometheus.MustNewConstMetric(lagDesc, prometheus.GaugeValue, float64(q.Lag), q.Queue)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(scheduledDesc, prometheus.GaugeValue, float64(s.Scheduled))
+	ch <- prometheus.MustNewConstMetric(dlqLengthDesc, prometheus.GaugeValue, float64(s.DeadLetters))
+}
@@ -0,0 +1,135 @@
+package redisq
+
+import (
+	"context"
+	"errors"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ ports.Canceller = (*Client)(nil)
+
+var ErrTaskNotFound = errors.New("task not found")
+
+// cancelScript cancels a queued or delayed task in place, releasing its
+// uniqueness lock and storing its result for waiters, and publishes the
+// request so a worker that already claimed it (or is about to start it) can
+// cancel the handler. For a running task the request is also kept in the
+// hash, in case its worker died and the reaper picks it up instead. Finished
+// tasks are left alone.
+//
+// The index and lock keys depend on the task's status and unique_key, so the
+// caller reads them first and the script returns '?' if they changed since.
+//
+// KEYS[1] task hash, KEYS[2] scheduled zset, KEYS[3] result key, KEYS[4]
+// index of the status read, KEYS[5] cancelled index, KEYS[6] uniqueness
+// lock (only when the task has one)
+// ARGV[1] task id, ARGV[2] status read, ARGV[3] unique_key read, ARGV[4]
+// cancel channel, ARGV[5] events channel prefix (see events.go), ARGV[6] now
+// (RFC 3339), ARGV[7] result channel (see result.go), ARGV[8] result TTL in
+// ms (0 = don't store), ARGV[9] error kept on the result
+var cancelScript = redis.NewScript(`
+local f = redis.call('HMGET', KEYS[1], 'status', 'unique_key', 'type', 'queue', 'attempts', 'created_at')
+local st = f[1]
+if not st then
+	return ''
+end
+if st == 'queued' or st == 'delayed' then
+	if st ~= ARGV[2] or (f[2] or '') ~= ARGV[3] then
+		return '?'
+	end
+	redis.call('ZREM', KEYS[2], ARGV[1])
+	redis.call('HSET', KEYS[1], 'status', 'cancelled')
+	redis.call('ZREM', KEYS[4], ARGV[1])
+	redis.call('ZADD', KEYS[5], f[6] or 0, ARGV[1])
+	if KEYS[6] and redis.call('GET', KEYS[6]) == ARGV[1] then
+		redis.call('DEL', KEYS[6])
+	end
+	redis.call('PUBLISH', ARGV[4], ARGV[1])
+	redis.call('PUBLISH', ARGV[5] .. (f[3] or ''), cjson.encode({
+		event = 'cancelled', task_id = ARGV[1], type = f[3] or '', queue = f[4] or '',
+		status = 'cancelled', attempts = tonumber(f[5]) or 0, at = ARGV[6],
+	}))
+	local res = cjson.encode({
+		task_id = ARGV[1], status = 'cancelled', error = ARGV[9],
+		attempts = tonumber(f[5]) or 0, finished_at = ARGV[6],
+	})
+	if tonumber(ARGV[8]) > 0 then
+		redis.call('SET', KEYS[3], res, 'PX', ARGV[8])
+	end
+	redis.call('PUBLISH', ARGV[7], res)
+	return 'cancelled'
+end
+if st == 'running' then
+	redis.call('HSET', KEYS[1], 'cancel_requested', '1')
+	redis.call('PUBLISH', ARGV[4], ARGV[1])
+end
+return st
+`)
+
+func (c *Client) cancelChannel() string { return c.Cfg.StreamKey + ":cancel" }
+
+func (c *Client) Cancel(ctx context.Context, id string) (domain.TaskStatus, error) {
+	// a task that moves on between the read and the script is read again
+	for range 3 {
+		f, err := c.Rdb.HMGet(ctx, taskKey(id), hashStatus, hashUniqueKey).Result()
+		if err != nil {
+			return "", err
+		}
+		read, _ := f[0].(string)
+		uk, _ := f[1].(string)
+
+		keys := []string{
+			taskKey(id), c.Cfg.ScheduledZSet, resultKey(id),
+			statusIndexKey(domain.TaskStatus(read)), statusIndexKey(domain.StatusCancelled),
+		}
+		if uk != "" {
+			keys = append(keys, c.uniqueKey(uk))
+		}
+		st, err := cancelScript.Run(ctx, c.Rdb, keys,
+			id, read, uk, c.cancelChannel(), c.eventsChannel(""),
+			time.Now().Format(time.RFC3339Nano), c.resultChannel(id),
+			c.Cfg.ResultTTL.Milliseconds(), cancelledReason,
+		).Text()
+		switch {
+		case err != nil:
+			return "", err
+		case st == "":
+			return "", ErrTaskNotFound
+		case st != "?":
+			return domain.TaskStatus(st), nil
+		}
+	}
+	return "", redis.TxFailedErr
+}
+
+func (c *Client) CancelRequests(ctx context.Context) <-chan string {
+	out := make(chan string)
+	ps := c.Rdb.Subscribe(ctx, c.cancelChannel())
+
+	go func() {
+		defer close(out)
+		defer ps.Close()
+
+		ch := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				select {
+				case out <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
@@ -14,6 +15,8 @@ import (
 type Client struct {
 	Cfg config.Redis
 	Rdb *redis.Client
+
+	results *resultWaiters
 }
 
 func New(cfg config.Redis) *Client {
@@ -23,7 +26,11 @@ func New(cfg config.Redis) *Client {
 		Password: cfg.Password,
 		DB:       cfg.DB,
 	})
-	return &Client{Cfg: cfg, Rdb: c}
+	// spans for every command, exported when tracing.Setup found an endpoint
+	if err := redisotel.InstrumentTracing(c); err != nil {
+		log.Error().Err(err).Msg("failed to instrument redis client for tracing")
+	}
+	return &Client{Cfg: cfg, Rdb: c, results: &resultWaiters{}}
 }
 
 // Connect → used by API only
@@ -35,22 +42,28 @@ func (c *Client) Connect(ctx context.Context) error {
 	return nil
 }
 
-// Init → used by Worker, ensures stream + group exist
+// Init → used by Worker, ensures stream + group exist for every configured queue
 func (c *Client) Init(ctx context.Context) error {
 	if err := c.Connect(ctx); err != nil {
 		return err
 	}
 
-	// Create stream and group if not exists
-	err := c.Rdb.XGroupCreateMkStream(ctx, c.Cfg.StreamKey, c.Cfg.Group, "0").Err()
-	if err != nil && !strings.Contains(err.Error(), "BUSYGROUP Consumer Group name already exists") {
-		return fmt.Errorf("failed to create consumer group: %w", err)
-	}
+	for _, q := range c.configured() {
+		// Create stream and group if not exists
+		err := c.Rdb.XGroupCreateMkStream(ctx, c.streamKey(q), c.Cfg.Group, "0").Err()
+		if err != nil && !strings.Contains(err.Error(), "BUSYGROUP Consumer Group name already exists") {
+			return fmt.Errorf("failed to create consumer group on queue %s: %w", q, err)
+		}
+		if err := c.Rdb.SAdd(ctx, c.queuesKey(), q).Err(); err != nil {
+			return err
+		}
 
-	log.Ctx(ctx).Info().
-		Str("stream", c.Cfg.StreamKey).
-		Str("group", c.Cfg.Group).
-		Msg("redis stream and consumer group ready")
+		log.Ctx(ctx).Info().
+			Str("queue", q).
+			Str("stream", c.streamKey(q)).
+			Str("group", c.Cfg.Group).
+			Msg("redis stream and consumer group ready")
+	}
 
 	return nil
 }
@@ -0,0 +1,87 @@
+package redisq
+
+import (
+	"context"
+	"redisq/internal/ports"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ ports.DLQ = (*Client)(nil)
+
+func (c *Client) DLQList(ctx context.Context, cursor string, count int64) ([]ports.DLQEntry, string, error) {
+	start := "-"
+	if cursor != "" {
+		start = "(" + cursor
+	}
+	msgs, err := c.Rdb.XRangeN(ctx, c.Cfg.DLQStreamKey, start, "+", count).Result()
+	if err != nil {
+		return nil, "", err
+	}
+
+	out := make([]ports.DLQEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		e, err := c.dlqEntry(ctx, msg)
+		if err != nil {
+			return nil, "", err
+		}
+		out = append(out, e)
+	}
+
+	var next string
+	if int64(len(msgs)) == count {
+		next = msgs[len(msgs)-1].ID
+	}
+	return out, next, nil
+}
+
+func (c *Client) DLQGet(ctx context.Context, streamID string) (*ports.DLQEntry, error) {
+	msgs, err := c.Rdb.XRange(ctx, c.Cfg.DLQStreamKey, streamID, streamID).Result()
+	if err != nil || len(msgs) == 0 {
+		return nil, err
+	}
+	e, err := c.dlqEntry(ctx, msgs[0])
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (c *Client) DLQDelete(ctx context.Context, streamIDs ...string) error {
+	if len(streamIDs) == 0 {
+		return nil
+	}
+	return c.Rdb.XDel(ctx, c.Cfg.DLQStreamKey, streamIDs...).Err()
+}
+
+// DLQPurge drops every DLQ entry and returns how many there were. Task state
+// is left alone so failed tasks stay inspectable.
+func (c *Client) DLQPurge(ctx context.Context) (int64, error) {
+	return c.Rdb.XTrimMaxLen(ctx, c.Cfg.DLQStreamKey, 0).Result()
+}
+
+func (c *Client) dlqEntry(ctx context.Context, msg redis.XMessage) (ports.DLQEntry, error) {
+	e := ports.DLQEntry{StreamID: msg.ID, At: streamIDTime(msg.ID)}
+	decoded, err := decodeEntry(msg.Values)
+	if err != nil {
+		// keep undecodable entries visible so they can be purged
+		e.Reason = err.Error()
+		return e, nil
+	}
+	e.TaskID, e.Reason = decoded.TaskID, decoded.Reason
+
+	t, err := c.Get(ctx, e.TaskID)
+	if err != nil {
+		return e, err
+	}
+	e.Task = t
+	return e, nil
+}
+
+// streamIDTime returns the time encoded in the ms part of a stream entry ID.
+func streamIDTime(id string) time.Time {
+	ms, _, _ := strings.Cut(id, "-")
+	return fromUnixMs(ms)
+}
@@ -0,0 +1,74 @@
+package redisq
+
+import (
+	"encoding/json"
+	"fmt"
+	"redisq/internal/domain"
+)
+
+// Every stream entry (main stream and DLQ) is written by encodeEntry and read
+// by decodeEntry, so the wire format lives in one place.
+//
+// v1 layout: {"v": "1", "task_id": <id>, "reason": <optional>}
+//
+// Older layouts are still decoded so queues can be upgraded in place:
+//   - {"task_id": <id>}         written by Enqueue
+//   - {"task": <task json>}     written by the scheduler and ToDLQ
+const (
+	entryVersion = "1"
+
+	fieldVersion = "v"
+	fieldTaskID  = "task_id"
+	fieldReason  = "reason"
+	fieldTask    = "task"
+)
+
+type entry struct {
+	TaskID string
+	Reason string
+}
+
+func encodeEntry(e entry) map[string]interface{} {
+	m := map[string]interface{}{
+		fieldVersion: entryVersion,
+		fieldTaskID:  e.TaskID,
+	}
+	if e.Reason != "" {
+		m[fieldReason] = e.Reason
+	}
+	return m
+}
+
+func decodeEntry(values map[string]interface{}) (entry, error) {
+	if v, ok := values[fieldVersion]; ok {
+		if v != entryVersion {
+			return entry{}, fmt.Errorf("unsupported stream entry version %v", v)
+		}
+		id, _ := values[fieldTaskID].(string)
+		if id == "" {
+			return entry{}, fmt.Errorf("task_id not found in message")
+		}
+		reason, _ := values[fieldReason].(string)
+		return entry{TaskID: id, Reason: reason}, nil
+	}
+
+	// legacy layouts
+	if id, ok := values[fieldTaskID].(string); ok && id != "" {
+		return entry{TaskID: id}, nil
+	}
+	if raw, ok := values[fieldTask].(string); ok {
+		var legacy struct {
+			domain.Task
+			Reason string `json:"reason"`
+		}
+		if err := json.Unmarshal([]byte(raw), &legacy); err != nil {
+			return entry{}, fmt.Errorf("invalid legacy task field: %w", err)
+		}
+		if legacy.ID == "" {
+			return entry{}, fmt.Errorf("task_id not found in message")
+		}
+		return entry{TaskID: legacy.ID, Reason: legacy.Reason}, nil
+	}
+
+	return entry{}, fmt.Errorf("task_id not found in message")
+}
@@ -0,0 +1,89 @@
+package redisq
+
+import (
+	"testing"
+)
+
+func TestDecodeEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]interface{}
+		want    entry
+		wantErr bool
+	}{
+		{
+			name:   "v1",
+			values: map[string]interface{}{"v": "1", "task_id": "t1"},
+			want:   entry{TaskID: "t1"},
+		},
+		{
+			name:   "v1 with reason",
+			values: map[string]interface{}{"v": "1", "task_id": "t1", "reason": "boom"},
+			want:   entry{TaskID: "t1", Reason: "boom"},
+		},
+		{
+			name:    "v1 without task_id",
+			values:  map[string]interface{}{"v": "1"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown version",
+			values:  map[string]interface{}{"v": "2", "task_id": "t1"},
+			wantErr: true,
+		},
+		{
+			name:   "legacy task_id",
+			values: map[string]interface{}{"task_id": "t1"},
+			want:   entry{TaskID: "t1"},
+		},
+		{
+			name:   "legacy task json",
+			values: map[string]interface{}{"task": `{"id":"t1","type":"email.send","attempts":2}`},
+			want:   entry{TaskID: "t1"},
+		},
+		{
+			name:   "legacy task json with reason",
+			values: map[string]interface{}{"task": `{"id":"t1","reason":"max attempts"}`},
+			want:   entry{TaskID: "t1", Reason: "max attempts"},
+		},
+		{
+			name:    "legacy task json without id",
+			values:  map[string]interface{}{"task": `{"type":"email.send"}`},
+			wantErr: true,
+		},
+		{
+			name:    "legacy task invalid json",
+			values:  map[string]interface{}{"task": `{`},
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			values:  map[string]interface{}{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeEntry(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeEntry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decodeEntry() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntryRoundTrip(t *testing.T) {
+	for _, e := range []entry{{TaskID: "t1"}, {TaskID: "t1", Reason: "boom"}} {
+		got, err := decodeEntry(encodeEntry(e))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != e {
+			t.Errorf("round trip = %+v, want %+v", got, e)
+		}
+	}
+}
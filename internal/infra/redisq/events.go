@@ -0,0 +1,72 @@
+package redisq
+
+import (
+	"context"
+	"encoding/json"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+var _ ports.EventStream = (*Client)(nil)
+
+// Lifecycle events are published per task type on <StreamKey>:events:<type>,
+// alongside every state write: SaveState, the guarded enqueue, and the
+// promote and cancel scripts. Pub/sub keeps no history; subscribers see what
+// happens while they listen.
+func (c *Client) eventsChannel(typ string) string { return c.Cfg.StreamKey + ":events:" + typ }
+
+// publishEvent queues the event on pipe, so it goes out with the state write.
+func (c *Client) publishEvent(ctx context.Context, pipe redis.Pipeliner, t domain.Task, event string) {
+	b, err := json.Marshal(domain.NewTaskEvent(t, event))
+	if err != nil {
+		return
+	}
+	pipe.Publish(ctx, c.eventsChannel(t.Type), b)
+}
+
+// Events subscribes before returning, so callers can read a task's current
+// state afterwards without missing a transition in between.
+func (c *Client) Events(ctx context.Context, typ string) (<-chan domain.TaskEvent, error) {
+	var ps *redis.PubSub
+	if typ == "" {
+		ps = c.Rdb.PSubscribe(ctx, c.eventsChannel("*"))
+	} else {
+		ps = c.Rdb.Subscribe(ctx, c.eventsChannel(typ))
+	}
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+
+	out := make(chan domain.TaskEvent)
+	go func() {
+		defer close(out)
+		defer ps.Close()
+
+		ch := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				var e domain.TaskEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+					log.Ctx(ctx).Error().Err(err).Str("channel", msg.Channel).Msg("invalid task event")
+					continue
+				}
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
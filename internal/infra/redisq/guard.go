@@ -0,0 +1,152 @@
+package redisq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrUniqueConflict is returned when a task with the same uniqueness key is
+// still queued, delayed or running.
+var ErrUniqueConflict = errors.New("a task with the same uniqueness key is already pending")
+
+// idempotencyKey is namespaced under the stream key, since keys are chosen by
+// clients and deployments may share a database.
+func (c *Client) idempotencyKey(key string) string { return c.Cfg.StreamKey + ":idempotency:" + key }
+
+// uniqueKey is the lock held by the pending task with t.UniqueKey; its value
+// is that task's ID. Like idempotency keys, locks are kept per deployment.
+func (c *Client) uniqueKey(key string) string { return c.Cfg.StreamKey + ":unique:" + key }
+
+// EnqueueGuarded enqueues t (delayed when runAt is set) under g. The guard
+// keys are checked under WATCH and written in the same MULTI as the task
+// hash, its indexes and its stream entry or ZSET member, so of two
+// concurrent requests exactly one enqueues.
+//
+// A repeated idempotency key returns the original task ID with dup set. A
+// held uniqueness lock fails with ErrUniqueConflict.
+func (c *Client) EnqueueGuarded(ctx context.Context, t domain.Task, runAt time.Time, g ports.Guard) (string, bool, error) {
+	if t.ID == "" {
+		t.ID = uuid.NewString()
+	}
+	if t.Queue == "" {
+		t.Queue = domain.DefaultQueue
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	t.Status = domain.StatusQueued
+	if !runAt.IsZero() {
+		t.Status = domain.StatusDelayed
+		t.NextRunAt = runAt
+	}
+
+	var watch []string
+	if g.IdempotencyKey != "" {
+		watch = append(watch, c.idempotencyKey(g.IdempotencyKey))
+	}
+	if t.UniqueKey != "" {
+		watch = append(watch, c.uniqueKey(t.UniqueKey))
+	}
+
+	var (
+		id  string
+		dup bool
+	)
+	txf := func(tx *redis.Tx) error {
+		if g.IdempotencyKey != "" {
+			prev, err := tx.Get(ctx, c.idempotencyKey(g.IdempotencyKey)).Result()
+			if err == nil {
+				id, dup = prev, true
+				return nil
+			}
+			if !errors.Is(err, redis.Nil) {
+				return err
+			}
+		}
+		if t.UniqueKey != "" {
+			holder, err := tx.Get(ctx, c.uniqueKey(t.UniqueKey)).Result()
+			if err == nil {
+				return fmt.Errorf("%w: task %s", ErrUniqueConflict, holder)
+			}
+			if !errors.Is(err, redis.Nil) {
+				return err
+			}
+		}
+
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if g.IdempotencyKey != "" {
+				pipe.Set(ctx, c.idempotencyKey(g.IdempotencyKey), t.ID, g.IdempotencyWindow)
+			}
+			if t.UniqueKey != "" {
+				pipe.Set(ctx, c.uniqueKey(t.UniqueKey), t.ID, g.UniqueTTL)
+			}
+			c.stage(ctx, pipe, t)
+			return nil
+		})
+		id = t.ID
+		return err
+	}
+
+	// a lost race means a guard key now exists, so the retry sees it
+	for range 3 {
+		err := c.Rdb.Watch(ctx, txf, watch...)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return "", false, err
+		}
+		if dup {
+			log.Ctx(ctx).Info().Str("idempotency_key", g.IdempotencyKey).Str("task_id", id).Msg("duplicate enqueue, returning original task")
+		}
+		return id, dup, nil
+	}
+	return "", false, redis.TxFailedErr
+}
+
+// stage queues t's state, indexes, lifecycle event and its stream entry, or
+// its scheduled ZSET member when t is delayed, on pipe.
+func (c *Client) stage(ctx context.Context, pipe redis.Pipeliner, t domain.Task) {
+	pipe.HSet(ctx, taskKey(t.ID), encodeTask(t))
+	indexTask(ctx, pipe, t)
+	c.publishEvent(ctx, pipe, t, string(t.Status))
+	if t.Status == domain.StatusDelayed {
+		pipe.ZAdd(ctx, c.Cfg.ScheduledZSet, redis.Z{Score: float64(t.NextRunAt.UnixMilli()), Member: t.ID})
+		return
+	}
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: c.streamKey(t.Queue),
+		Values: encodeEntry(entry{TaskID: t.ID}),
+	})
+}
+
+// releaseUniqueScript drops a uniqueness lock, but only if it still belongs
+// to the task: after it expired another task may have taken it.
+//
+// KEYS[1] lock, ARGV[1] task id
+var releaseUniqueScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// releaseUnique queues the release of t's uniqueness lock on pipe once t has
+// reached a final state.
+func (c *Client) releaseUnique(ctx context.Context, pipe redis.Pipeliner, t domain.Task) {
+	if t.UniqueKey == "" {
+		return
+	}
+	switch t.Status {
+	case domain.StatusDone, domain.StatusFailed, domain.StatusDiscarded, domain.StatusCancelled:
+		releaseUniqueScript.Eval(ctx, pipe, []string{c.uniqueKey(t.UniqueKey)}, t.ID)
+	}
+}
@@ -0,0 +1,39 @@
+package redisq
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// touchScript resets the idle time of pending entries the consumer still
+// owns, so the reaper leaves entries of live, long-running handlers alone.
+// Entries acked or already reclaimed by the reaper are skipped rather than
+// claimed back.
+//
+// KEYS[1] stream
+// ARGV[1] group, ARGV[2] consumer, ARGV[3..] stream IDs
+var touchScript = redis.NewScript(`
+local n = 0
+for i = 3, #ARGV do
+	local p = redis.call('XPENDING', KEYS[1], ARGV[1], ARGV[i], ARGV[i], 1, ARGV[2])
+	if #p > 0 then
+		redis.call('XCLAIM', KEYS[1], ARGV[1], ARGV[2], 0, ARGV[i], 'JUSTID')
+		n = n + 1
+	end
+end
+return n
+`)
+
+// Touch resets the idle time of consumer's pending entries in queue.
+func (c *Client) Touch(ctx context.Context, consumer, queue string, streamIDs ...string) error {
+	if len(streamIDs) == 0 {
+		return nil
+	}
+	args := make([]any, 0, 2+len(streamIDs))
+	args = append(args, c.Cfg.Group, consumer)
+	for _, id := range streamIDs {
+		args = append(args, id)
+	}
+	return touchScript.Run(ctx, c.Rdb, []string{c.streamKey(queue)}, args...).Err()
+}
@@ -0,0 +1,115 @@
+package redisq
+
+import (
+	"context"
+	"errors"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ ports.TaskLister = (*Client)(nil)
+
+// Secondary indexes, maintained by SaveState (and the scheduler's promote
+// script), so listings never scan task:* keys. Both are ZSETs of task IDs
+// scored by created_at in ms.
+const (
+	statusIndexPrefix = "tasks:status:"
+	typeIndexPrefix   = "tasks:type:"
+	// status and type intersections, see List
+	listInterPrefix = "tasks:list:"
+)
+
+func statusIndexKey(s domain.TaskStatus) string { return statusIndexPrefix + string(s) }
+func typeIndexKey(typ string) string            { return typeIndexPrefix + typ }
+
+// indexTask queues the index updates for t on pipe: the task leaves every
+// other status index and joins its current one.
+func indexTask(ctx context.Context, pipe redis.Pipeliner, t domain.Task) {
+	z := redis.Z{Score: float64(unixMs(t.CreatedAt)), Member: t.ID}
+	for _, s := range domain.Statuses {
+		if s != t.Status {
+			pipe.ZRem(ctx, statusIndexKey(s), t.ID)
+		}
+	}
+	if t.Status != "" {
+		pipe.ZAdd(ctx, statusIndexKey(t.Status), z)
+	}
+	if t.Type != "" {
+		pipe.ZAdd(ctx, typeIndexKey(t.Type), z)
+	}
+}
+
+// listInterTTL is how long a status and type intersection is kept, so the
+// pages of one listing share it instead of each intersecting again. Such
+// listings lag the indexes by up to this long.
+const listInterTTL = 5 * time.Second
+
+// listInterScript returns the size and one page of the intersection of two
+// indexes, stored under KEYS[1] unless a recent one still is.
+//
+// KEYS[1] intersection, KEYS[2] and KEYS[3] indexes
+// ARGV[1] TTL in ms, ARGV[2] start, ARGV[3] stop
+var listInterScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	redis.call('ZINTERSTORE', KEYS[1], 2, KEYS[2], KEYS[3], 'AGGREGATE', 'MAX')
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {redis.call('ZCARD', KEYS[1]), redis.call('ZREVRANGE', KEYS[1], ARGV[2], ARGV[3])}
+`)
+
+// List pages through the status and/or type indexes. With both set it
+// intersects them.
+func (c *Client) List(ctx context.Context, f ports.TaskFilter) ([]domain.Task, int64, error) {
+	var keys []string
+	if f.Status != "" {
+		keys = append(keys, statusIndexKey(f.Status))
+	}
+	if f.Type != "" {
+		keys = append(keys, typeIndexKey(f.Type))
+	}
+	if len(keys) == 0 {
+		return nil, 0, errors.New("status or type filter required")
+	}
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+
+	var ids []string
+	var total int64
+	if len(keys) == 1 {
+		var err error
+		if total, err = c.Rdb.ZCard(ctx, keys[0]).Result(); err != nil {
+			return nil, 0, err
+		}
+		if ids, err = c.Rdb.ZRevRange(ctx, keys[0], f.Offset, f.Offset+f.Limit-1).Result(); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		res, err := listInterScript.Run(ctx, c.Rdb,
+			[]string{listInterPrefix + string(f.Status) + ":" + f.Type, keys[0], keys[1]},
+			listInterTTL.Milliseconds(), f.Offset, f.Offset+f.Limit-1,
+		).Slice()
+		if err != nil {
+			return nil, 0, err
+		}
+		total, _ = res[0].(int64)
+		for _, id := range res[1].([]any) {
+			ids = append(ids, id.(string))
+		}
+	}
+
+	tasks := make([]domain.Task, 0, len(ids))
+	for _, id := range ids {
+		t, err := c.Get(ctx, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		if t != nil {
+			tasks = append(tasks, *t)
+		}
+	}
+	return tasks, total, nil
+}
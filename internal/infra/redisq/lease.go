@@ -0,0 +1,58 @@
+package redisq
+
+import (
+	"context"
+	"redisq/internal/ports"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ ports.Lease = (*Client)(nil)
+
+// The lease is LeaderKey = owner with a PX expiry. Every new term bumps
+// LeaderKey:fence, whose value is the fencing token handed to the leader.
+func (c *Client) fenceKey() string { return c.Cfg.LeaderKey + ":fence" }
+
+// acquireScript takes the lease if it is free (new term, new token) or renews
+// it if owner holds it already.
+//
+// KEYS[1] lease, KEYS[2] fence
+// ARGV[1] owner, ARGV[2] ttl (ms)
+var acquireScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return tonumber(redis.call('GET', KEYS[2]) or 0)
+end
+return 0
+`)
+
+// releaseScript deletes the lease only if owner still holds it.
+//
+// KEYS[1] lease
+// ARGV[1] owner
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func (c *Client) Acquire(ctx context.Context, owner string, ttl time.Duration) (int64, error) {
+	return acquireScript.Run(ctx, c.Rdb, []string{c.Cfg.LeaderKey, c.fenceKey()}, owner, ttl.Milliseconds()).Int64()
+}
+
+func (c *Client) Release(ctx context.Context, owner string) error {
+	return releaseScript.Run(ctx, c.Rdb, []string{c.Cfg.LeaderKey}, owner).Err()
+}
+
+func (c *Client) Valid(ctx context.Context, token int64) (bool, error) {
+	cur, err := c.Rdb.Get(ctx, c.fenceKey()).Int64()
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+	return cur == token, nil
+}
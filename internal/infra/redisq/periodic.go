@@ -0,0 +1,84 @@
+package redisq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ ports.PeriodicStore = (*Client)(nil)
+
+// Periodic definitions live in the PeriodicKey hash (field = name, value =
+// JSON), their last fired times (ms) in PeriodicKey:fired.
+func (c *Client) firedKey() string { return c.Cfg.PeriodicKey + ":fired" }
+
+func (c *Client) Periodics(ctx context.Context) ([]domain.Periodic, error) {
+	h, err := c.Rdb.HGetAll(ctx, c.Cfg.PeriodicKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Periodic, 0, len(h))
+	for name, raw := range h {
+		var p domain.Periodic
+		if err := json.Unmarshal([]byte(raw), &p); err != nil {
+			return nil, fmt.Errorf("invalid periodic task %s: %w", name, err)
+		}
+		p.Name = name
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (c *Client) SavePeriodic(ctx context.Context, p domain.Periodic) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return c.Rdb.HSet(ctx, c.Cfg.PeriodicKey, p.Name, b).Err()
+}
+
+func (c *Client) DeletePeriodic(ctx context.Context, name string) error {
+	pipe := c.Rdb.TxPipeline()
+	pipe.HDel(ctx, c.Cfg.PeriodicKey, name)
+	pipe.HDel(ctx, c.firedKey(), name)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *Client) LastFired(ctx context.Context, name string) (time.Time, error) {
+	v, err := c.Rdb.HGet(ctx, c.firedKey(), name).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fromUnixMs(v), nil
+}
+
+// advanceScript is a compare-and-set on one field of the fired hash.
+//
+// KEYS[1] fired hash
+// ARGV[1] name, ARGV[2] expected (ms, "0" if unset), ARGV[3] new (ms)
+var advanceScript = redis.NewScript(`
+local cur = redis.call('HGET', KEYS[1], ARGV[1]) or '0'
+if cur ~= ARGV[2] then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+return 1
+`)
+
+func (c *Client) AdvanceFired(ctx context.Context, name string, prev, at time.Time) (bool, error) {
+	ok, err := advanceScript.Run(ctx, c.Rdb, []string{c.firedKey()},
+		name, strconv.FormatInt(unixMs(prev), 10), strconv.FormatInt(unixMs(at), 10),
+	).Int()
+	return ok == 1, err
+}
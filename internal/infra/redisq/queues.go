@@ -0,0 +1,48 @@
+package redisq
+
+import (
+	"context"
+	"redisq/internal/domain"
+	"sort"
+)
+
+// Every named queue is a stream of its own. The default queue keeps the bare
+// StreamKey so deployments without named queues see no change; any other
+// queue lives at StreamKey:<queue>.
+func (c *Client) streamKey(queue string) string {
+	if queue == "" || queue == domain.DefaultQueue {
+		return c.Cfg.StreamKey
+	}
+	return c.Cfg.StreamKey + ":" + queue
+}
+
+// queuesKey is a set of every queue some worker has created a consumer group
+// on, so leader-only loops (the reaper) cover queues they don't consume.
+func (c *Client) queuesKey() string { return c.Cfg.StreamKey + ":queues" }
+
+// configured returns the default queue followed by Cfg.Queues, deduplicated.
+func (c *Client) configured() []string {
+	out := []string{domain.DefaultQueue}
+	seen := map[string]bool{domain.DefaultQueue: true}
+	for _, q := range c.Cfg.Queues {
+		if q == "" || seen[q] {
+			continue
+		}
+		seen[q] = true
+		out = append(out, q)
+	}
+	return out
+}
+
+// Queues returns every queue with a consumer group, sorted by name.
+func (c *Client) Queues(ctx context.Context) ([]string, error) {
+	qs, err := c.Rdb.SMembers(ctx, c.queuesKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(qs) == 0 {
+		qs = c.configured()
+	}
+	sort.Strings(qs)
+	return qs, nil
+}
@@ -0,0 +1,180 @@
+package redisq
+
+import (
+	"context"
+	"errors"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+var _ ports.Reaper = (*Reaper)(nil)
+
+const (
+	reapReason      = "visibility timeout exceeded"
+	cancelledReason = "task cancelled"
+)
+
+// Reaper recovers stream entries left in the consumer group's pending list
+// by consumers that died (or hung) between Claim and Ack.
+type Reaper struct {
+	C                 *Client
+	Consumer          string
+	Interval          time.Duration
+	VisibilityTimeout time.Duration
+	// Token, when set, is the leader fencing token; reaping stops once a
+	// newer leader term has started.
+	Token int64
+	// Metrics, when set, counts recovered tasks as retried.
+	Metrics ports.Metrics
+	// ResultTTL is how long the result of tasks the reaper cancels is kept,
+	// like the consumer does. Waiters are woken even when it is zero.
+	ResultTTL time.Duration
+}
+
+func NewReaper(c *Client, consumer string, interval, visibilityTimeout time.Duration) *Reaper {
+	return &Reaper{C: c, Consumer: consumer, Interval: interval, VisibilityTimeout: visibilityTimeout}
+}
+
+func (r *Reaper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := r.reap(ctx); err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("reaper reap failed")
+			}
+		}
+	}
+}
+
+// reap walks the pending list of every queue with XAUTOCLAIM and hands every
+// entry idle for longer than the visibility timeout back to its stream,
+// counting the lost delivery as an attempt.
+func (r *Reaper) reap(ctx context.Context) error {
+	if r.Token != 0 {
+		ok, err := r.C.Valid(ctx, r.Token)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrFenced
+		}
+	}
+
+	queues, err := r.C.Queues(ctx)
+	if err != nil {
+		return err
+	}
+	for _, q := range queues {
+		if err := r.reapQueue(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reaper) reapQueue(ctx context.Context, queue string) error {
+	start := "0-0"
+	for {
+		msgs, next, err := r.C.Rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   r.C.streamKey(queue),
+			Group:    r.C.Cfg.Group,
+			Consumer: r.Consumer,
+			MinIdle:  r.VisibilityTimeout,
+			Start:    start,
+			Count:    128,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return nil
+			}
+			return err
+		}
+
+		for _, msg := range msgs {
+			r.recover(ctx, queue, msg)
+		}
+
+		if next == "0-0" || next == "" {
+			return nil
+		}
+		start = next
+	}
+}
+
+func (r *Reaper) recover(ctx context.Context, queue string, msg redis.XMessage) {
+	e, err := decodeEntry(msg.Values)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("stream_id", msg.ID).Msg("reaper failed to decode entry")
+		_ = r.C.Ack(ctx, queue, msg.ID)
+		return
+	}
+	t, err := r.C.Get(ctx, e.TaskID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("stream_id", msg.ID).Msg("reaper failed to load task")
+		return
+	}
+	if t == nil || t.Status.Final() {
+		// nothing left to retry (or finished after all), drop the entry
+		_ = r.C.Ack(ctx, queue, msg.ID)
+		return
+	}
+
+	t.Queue = queue
+	if t.CancelRequested {
+		// cancelled while its worker was gone
+		_ = r.C.Ack(ctx, queue, msg.ID)
+		t.Status, t.LastError = domain.StatusCancelled, cancelledReason
+		r.saveResult(ctx, *t)
+		if err := r.C.SaveState(ctx, *t); err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("task_id", t.ID).Msg("reaper failed to cancel task")
+		}
+		return
+	}
+
+	if t.HandedBack {
+		// left pending by a worker shutting down, not lost
+		t.HandedBack = false
+		if err := r.C.Requeue(ctx, msg.ID, *t); err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("task_id", t.ID).Msg("reaper failed to requeue handed back task")
+		}
+		return
+	}
+
+	// The lost delivery counts as an attempt. Whether the task gets
+	// another one is left to the consumer claiming it next, which resolves
+	// max_attempts and on_exhausted like it does for failed attempts.
+	t.Attempts++
+	t.LastError = reapReason
+	if err := r.C.Requeue(ctx, msg.ID, *t); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("task_id", t.ID).Msg("reaper failed to requeue task")
+		return
+	}
+	if r.Metrics != nil {
+		r.Metrics.Retried(*t)
+	}
+	log.Ctx(ctx).Info().Str("task_id", t.ID).Int("attempts", t.Attempts).Msg("reaper recovered stuck task")
+}
+
+// saveResult stores the outcome of a task the reaper finished, so waiters
+// see it like one a worker finished.
+func (r *Reaper) saveResult(ctx context.Context, t domain.Task) {
+	res := domain.TaskResult{
+		TaskID:     t.ID,
+		Status:     t.Status,
+		Error:      t.LastError,
+		Attempts:   t.Attempts,
+		FinishedAt: time.Now(),
+	}
+	if err := r.C.SaveResult(ctx, res, r.ResultTTL); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("task_id", t.ID).Msg("reaper failed to store task result")
+	}
+}
@@ -0,0 +1,142 @@
+package redisq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+var _ ports.ResultStore = (*Client)(nil)
+
+// Results live next to the task hash, as JSON under task:<id>:result, and
+// expire on their own TTL while the task hash stays.
+func resultKey(id string) string { return taskKey(id) + ":result" }
+
+// resultChannel is where a task's result is published as it is stored, for
+// callers blocked in WaitResult (see resultWaiters).
+func (c *Client) resultChannel(id string) string { return c.Cfg.StreamKey + ":result:" + id }
+
+func (c *Client) SaveResult(ctx context.Context, r domain.TaskResult, ttl time.Duration) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = c.Rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if ttl > 0 {
+			pipe.Set(ctx, resultKey(r.TaskID), b, ttl)
+		}
+		pipe.Publish(ctx, c.resultChannel(r.TaskID), b)
+		return nil
+	})
+	return err
+}
+
+func (c *Client) Result(ctx context.Context, id string) (*domain.TaskResult, error) {
+	b, err := c.Rdb.Get(ctx, resultKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var r domain.TaskResult
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// WaitResult registers with the client's result subscription before reading
+// the stored result, so a result saved in between is not missed either way.
+func (c *Client) WaitResult(ctx context.Context, id string) (*domain.TaskResult, error) {
+	ch, err := c.results.add(ctx, c, id)
+	if err != nil {
+		return nil, err
+	}
+	defer c.results.remove(id, ch)
+
+	if r, err := c.Result(ctx, id); err != nil || r != nil {
+		return r, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		return r, nil
+	}
+}
+
+// resultWaiters fans the results published on <StreamKey>:result:* out to
+// the client's WaitResult callers, so they share one pub/sub connection
+// instead of holding one each. The subscription starts with the first waiter
+// and lives as long as the client.
+type resultWaiters struct {
+	mu      sync.Mutex
+	ps      *redis.PubSub
+	waiters map[string][]chan *domain.TaskResult
+}
+
+// add registers a waiter for id once the subscription is confirmed, so any
+// result published afterwards reaches it.
+func (w *resultWaiters) add(ctx context.Context, c *Client, id string) (chan *domain.TaskResult, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.ps == nil {
+		ps := c.Rdb.PSubscribe(context.WithoutCancel(ctx), c.resultChannel("*"))
+		if _, err := ps.Receive(ctx); err != nil {
+			_ = ps.Close()
+			return nil, err
+		}
+		w.ps = ps
+		w.waiters = map[string][]chan *domain.TaskResult{}
+		go w.dispatch(c.resultChannel(""))
+	}
+
+	// buffered, so dispatch never blocks on a waiter
+	ch := make(chan *domain.TaskResult, 1)
+	w.waiters[id] = append(w.waiters[id], ch)
+	return ch, nil
+}
+
+func (w *resultWaiters) remove(id string, ch chan *domain.TaskResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.waiters[id] = slices.DeleteFunc(w.waiters[id], func(c chan *domain.TaskResult) bool { return c == ch })
+	if len(w.waiters[id]) == 0 {
+		delete(w.waiters, id)
+	}
+}
+
+func (w *resultWaiters) dispatch(prefix string) {
+	for msg := range w.ps.Channel() {
+		var r domain.TaskResult
+		if err := json.Unmarshal([]byte(msg.Payload), &r); err != nil {
+			log.Error().Err(err).Str("channel", msg.Channel).Msg("invalid task result")
+			continue
+		}
+		id := strings.TrimPrefix(msg.Channel, prefix)
+
+		w.mu.Lock()
+		for _, ch := range w.waiters[id] {
+			r := r
+			select {
+			case ch <- &r:
+			default:
+			}
+		}
+		w.mu.Unlock()
+	}
+}
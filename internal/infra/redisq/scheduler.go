@@ -3,6 +3,7 @@ package redisq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"redisq/internal/domain"
 	"redisq/internal/ports"
 	"strconv"
@@ -14,9 +15,19 @@ import (
 
 var _ ports.Scheduler = (*Scheduler)(nil)
 
+// ErrFenced is returned by leader-only loops once a newer leader term exists.
+var ErrFenced = errors.New("fenced off by a newer leader")
+
 type Scheduler struct {
 	C        *Client
 	Interval time.Duration
+	// Token, when set, is the leader fencing token; promotion stops once a
+	// newer leader term has started.
+	Token int64
+	// Types, when set, supplies the queue of tasks stored without one, e.g.
+	// by producers that predate named queues. Everything else was resolved
+	// by the Enqueuer and is read from the task hash.
+	Types ports.TypeResolver
 }
 
 func NewScheduler(c *Client, interval time.Duration) *Scheduler {
@@ -40,42 +51,129 @@ func (s *Scheduler) Run(ctx context.Context) error {
 	}
 }
 
+const promoteBatch = 128
+
+// promoteScript moves due tasks from the scheduled ZSET onto their queue's
+// stream in one atomic step. moveDue resolves every key and builds the
+// lifecycle events beforehand, so key naming stays in Go; the script only
+// skips tasks that another scheduler or a cancel removed first, so none is
+// promoted twice.
+//
+// KEYS[1] scheduled zset, KEYS[2] leader fence (see lease.go), KEYS[3]
+// delayed status index, KEYS[4] queued status index (see index.go), then per
+// task: its hash and its queue's stream
+// ARGV[1] fencing token ("0" = unfenced), ARGV[2] stream entry version (see
+// entry.go), then per task: its id, index score (created_at ms), events
+// channel and queued event (see events.go)
+var promoteScript = redis.NewScript(`
+if ARGV[1] ~= '0' and redis.call('GET', KEYS[2]) ~= ARGV[1] then
+	return -1
+end
+local n = 0
+for i = 0, (#KEYS - 4) / 2 - 1 do
+	local key, stream = KEYS[5 + 2 * i], KEYS[6 + 2 * i]
+	local id, score, channel, event = ARGV[3 + 4 * i], ARGV[4 + 4 * i], ARGV[5 + 4 * i], ARGV[6 + 4 * i]
+	if redis.call('ZREM', KEYS[1], id) == 1 then
+		redis.call('XADD', stream, '*', 'v', ARGV[2], 'task_id', id)
+		redis.call('HSET', key, 'status', 'queued')
+		redis.call('ZREM', KEYS[3], id)
+		redis.call('ZADD', KEYS[4], score, id)
+		redis.call('PUBLISH', channel, event)
+		n = n + 1
+	end
+end
+return n
+`)
+
+// moveDue promotes every task that is due, batch by batch, until the backlog
+// is drained.
 func (s *Scheduler) moveDue(ctx context.Context) error {
-	now := nowMs()
-	ids, err := s.C.Rdb.ZRangeByScore(ctx, s.C.Cfg.ScheduledZSet, &redis.ZRangeBy{
-		Min:    "-inf",
-		Max:    fmtFloat(now),
-		Offset: 0,
-		Count:  128,
-	}).Result()
-	if err != nil {
-		return err // only return actual Redis error
+	now := fmtFloat(nowMs())
+	for {
+		ids, err := s.C.Rdb.ZRangeByScore(ctx, s.C.Cfg.ScheduledZSet, &redis.ZRangeBy{
+			Min: "-inf", Max: now, Count: promoteBatch,
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		n, err := s.promote(ctx, ids)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Ctx(ctx).Debug().Int("count", n).Msg("promoted due tasks")
+		}
+		if len(ids) < promoteBatch {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// promote loads the tasks to resolve their streams, then moves them.
+func (s *Scheduler) promote(ctx context.Context, ids []string) (int, error) {
+	pipe := s.C.Rdb.Pipeline()
+	hashes := make([]*redis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		hashes[i] = pipe.HGetAll(ctx, taskKey(id))
 	}
-	if len(ids) == 0 {
-		return nil // nothing to move this tick
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
 	}
 
-	for _, id := range ids {
-		key := "task:" + id
-		h, err := s.C.Rdb.HGetAll(ctx, key).Result()
+	keys := []string{
+		s.C.Cfg.ScheduledZSet, s.C.fenceKey(),
+		statusIndexKey(domain.StatusDelayed), statusIndexKey(domain.StatusQueued),
+	}
+	args := []any{strconv.FormatInt(s.Token, 10), entryVersion}
+	// IDs without a usable hash are dropped rather than promoted: the
+	// script would otherwise create a bare hash that runs as a typeless task
+	var orphans []string
+	for i, id := range ids {
+		h := hashes[i].Val()
+		if len(h) == 0 {
+			log.Ctx(ctx).Warn().Str("task_id", id).Msg("dropping scheduled task without a hash")
+			orphans = append(orphans, id)
+			continue
+		}
+		t, err := decodeTask(id, h)
 		if err != nil {
-			log.Ctx(ctx).Error().Err(err).Str("task_id", id).Msg("failed to fetch task hash")
+			log.Ctx(ctx).Error().Err(err).Str("task_id", id).Msg("dropping scheduled task that failed to decode")
+			orphans = append(orphans, id)
 			continue
 		}
+		if t.Queue == "" && s.Types != nil {
+			t.Queue = s.Types.Lookup(ctx, t.Type).Queue
+		}
+		if t.Queue == "" {
+			t.Queue = domain.DefaultQueue
+		}
+		t.Status = domain.StatusQueued
 
-		t := domain.Task{ID: id, Type: h["type"], Status: domain.StatusQueued}
-		b, _ := json.Marshal(t)
+		event, _ := json.Marshal(domain.NewTaskEvent(*t, string(t.Status)))
+		keys = append(keys, taskKey(id), s.C.streamKey(t.Queue))
+		args = append(args, id, unixMs(t.CreatedAt), s.C.eventsChannel(t.Type), string(event))
+	}
 
-		if _, err := s.C.Rdb.XAdd(ctx, &redis.XAddArgs{
-			Stream: s.C.Cfg.StreamKey,
-			Values: map[string]interface{}{"task": b},
-		}).Result(); err == nil {
-			_ = s.C.Rdb.ZRem(ctx, s.C.Cfg.ScheduledZSet, id).Err()
-		} else {
-			log.Ctx(ctx).Error().Err(err).Str("task_id", id).Msg("failed to XADD task")
+	n, err := promoteScript.Run(ctx, s.C.Rdb, keys, args...).Int()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, ErrFenced
+	}
+	if len(orphans) > 0 {
+		if err := s.C.Rdb.ZRem(ctx, s.C.Cfg.ScheduledZSet, orphans).Err(); err != nil {
+			return n, err
 		}
 	}
-	return nil
+	return n, nil
 }
 
 func fmtFloat(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
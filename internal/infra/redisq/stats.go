@@ -0,0 +1,42 @@
+package redisq
+
+import (
+	"context"
+	"redisq/internal/ports"
+)
+
+var _ ports.StatsReader = (*Client)(nil)
+
+// Stats reads queue depths straight from the stream, group and ZSET keys.
+func (c *Client) Stats(ctx context.Context) (ports.Stats, error) {
+	queues, err := c.Queues(ctx)
+	if err != nil {
+		return ports.Stats{}, err
+	}
+
+	var s ports.Stats
+	for _, q := range queues {
+		qs := ports.QueueStats{Queue: q, Lag: -1}
+		if qs.Length, err = c.Rdb.XLen(ctx, c.streamKey(q)).Result(); err != nil {
+			return ports.Stats{}, err
+		}
+		groups, err := c.Rdb.XInfoGroups(ctx, c.streamKey(q)).Result()
+		if err != nil {
+			return ports.Stats{}, err
+		}
+		for _, g := range groups {
+			if g.Name == c.Cfg.Group {
+				qs.Pending, qs.Lag = g.Pending, g.Lag
+			}
+		}
+		s.Queues = append(s.Queues, qs)
+	}
+
+	if s.Scheduled, err = c.Rdb.ZCard(ctx, c.Cfg.ScheduledZSet).Result(); err != nil {
+		return ports.Stats{}, err
+	}
+	if s.DeadLetters, err = c.Rdb.XLen(ctx, c.Cfg.DLQStreamKey).Result(); err != nil {
+		return ports.Stats{}, err
+	}
+	return s, nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"redisq/internal/domain"
 	"redisq/internal/ports"
 	"time"
@@ -20,27 +19,36 @@ func (c *Client) Enqueue(ctx context.Context, t domain.Task) (string, error) {
 	if t.ID == "" {
 		t.ID = uuid.NewString()
 	}
+	if t.Queue == "" {
+		t.Queue = domain.DefaultQueue
+	}
 
 	t.Status = domain.StatusQueued
-	t.CreatedAt = time.Now()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
 	if err := c.SaveState(ctx, t); err != nil {
 		return "", err
 	}
-	id, err := c.Rdb.XAdd(ctx, &redis.XAddArgs{
-		Stream: c.Cfg.StreamKey,
-		Values: map[string]interface{}{"task_id": t.ID},
-	}).Result()
-
-	if err != nil {
+	if err := c.Rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: c.streamKey(t.Queue),
+		Values: encodeEntry(entry{TaskID: t.ID}),
+	}).Err(); err != nil {
 		return "", err
 	}
-	return id, nil
+	return t.ID, nil
 }
 
 func (c *Client) EnqueueDelayed(ctx context.Context, t domain.Task, runAt time.Time) (string, error) {
 	if t.ID == "" {
 		t.ID = uuid.NewString()
 	}
+	if t.Queue == "" {
+		t.Queue = domain.DefaultQueue
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
 	t.Status = domain.StatusDelayed
 	t.NextRunAt = runAt
 	if err := c.SaveState(ctx, t); err != nil {
@@ -54,102 +62,187 @@ func (c *Client) EnqueueDelayed(ctx context.Context, t domain.Task, runAt time.T
 	return t.ID, nil
 }
 
-func (c *Client) Claim(ctx context.Context, consumer string, block time.Duration) (*domain.Task, string, error) {
+// Claim reads up to count new entries for consumer, trying queues in the
+// order given: a queue is only read once the ones before it came up empty.
+// If every queue is empty it blocks for up to block (<= 0 means don't wait)
+// until any of them gets an entry. Entries that can't be decoded or whose
+// task hash is gone are acked and skipped, so they don't sit in the pending
+// list forever.
+func (c *Client) Claim(ctx context.Context, consumer string, queues []string, count int, block time.Duration) ([]ports.Delivery, error) {
+	if len(queues) == 0 {
+		queues = []string{domain.DefaultQueue}
+	}
+
+	var out []ports.Delivery
+	for _, q := range queues {
+		if len(out) >= count {
+			return out, nil
+		}
+		ds, err := c.read(ctx, consumer, []string{q}, count-len(out), 0)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, ds...)
+	}
+	if len(out) > 0 || block <= 0 {
+		return out, nil
+	}
+
+	// A blocked read is woken by the first stream to get an entry and only
+	// returns that stream. Entries already waiting on several streams (added
+	// since the pass above) can overshoot count; those are handed back.
+	ds, err := c.read(ctx, consumer, queues, count, block)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range ds[min(count, len(ds)):] {
+		if d.Task.Status.Final() {
+			_ = c.Ack(ctx, d.Task.Queue, d.StreamID)
+			continue
+		}
+		if err := c.Requeue(ctx, d.StreamID, d.Task); err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("task_id", d.Task.ID).Msg("failed to hand back surplus entry")
+		}
+	}
+	return ds[:min(count, len(ds))], nil
+}
+
+// read runs one XREADGROUP over queues. block <= 0 doesn't block.
+func (c *Client) read(ctx context.Context, consumer string, queues []string, count int, block time.Duration) ([]ports.Delivery, error) {
+	streams := make([]string, 0, 2*len(queues))
+	byStream := make(map[string]string, len(queues))
+	for _, q := range queues {
+		streams = append(streams, c.streamKey(q))
+		byStream[c.streamKey(q)] = q
+	}
+	for range queues {
+		streams = append(streams, ">")
+	}
+	if block <= 0 {
+		block = -1
+	}
+
 	args := &redis.XReadGroupArgs{
 		Group:    c.Cfg.Group,
 		Consumer: consumer,
-		Streams:  []string{c.Cfg.StreamKey, ">"},
-		Count:    1,
+		Streams:  streams,
+		Count:    int64(count),
 		Block:    block,
 	}
-	log.Ctx(ctx).Info().Msgf("XReadGroup args: %+v", args)
+	log.Ctx(ctx).Debug().Msgf("XReadGroup args: %+v", args)
 	res, err := c.Rdb.XReadGroup(ctx, args).Result()
 
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return nil, "", nil
+			return nil, nil
 		}
-		return nil, "", err
+		return nil, err
 	}
 
-	if len(res) == 0 || len(res[0].Messages) == 0 {
-		return nil, "", nil
+	var out []ports.Delivery
+	for _, s := range res {
+		queue := byStream[s.Stream]
+		for _, msg := range s.Messages {
+			e, err := decodeEntry(msg.Values)
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Str("stream_id", msg.ID).Msg("dropping undecodable entry")
+				_ = c.Ack(ctx, queue, msg.ID)
+				continue
+			}
+			t, err := c.Get(ctx, e.TaskID)
+			if err != nil {
+				// left pending, the reaper picks it up after the visibility timeout
+				log.Ctx(ctx).Error().Err(err).Str("task_id", e.TaskID).Msg("failed to load claimed task")
+				continue
+			}
+			if t == nil {
+				log.Ctx(ctx).Error().Str("task_id", e.TaskID).Msg("dropping entry for missing task")
+				_ = c.Ack(ctx, queue, msg.ID)
+				continue
+			}
+			// the stream it came from is authoritative, e.g. for legacy hashes
+			t.Queue = queue
+			out = append(out, ports.Delivery{StreamID: msg.ID, Task: *t})
+		}
 	}
+	return out, nil
+}
 
-	msg := res[0].Messages[0]
-	taskID, ok := msg.Values["task_id"].(string)
-	if !ok {
-		return nil, "", fmt.Errorf("task_id not found in message")
-	}
-	t, err := c.Get(ctx, taskID)
-	if err != nil {
-		return nil, "", err
+func (c *Client) Ack(ctx context.Context, queue, streamID string) error {
+	return c.Rdb.XAck(ctx, c.streamKey(queue), c.Cfg.Group, streamID).Err()
+}
+
+// Requeue acks streamID and appends the task to its queue's stream again, so it is
+// delivered to whichever consumer reads next. Callers check that t isn't
+// final or cancel-requested; Requeue puts it back regardless.
+func (c *Client) Requeue(ctx context.Context, streamID string, t domain.Task) error {
+	t.Status = domain.StatusQueued
+	if err := c.SaveState(ctx, t); err != nil {
+		return err
 	}
-	return t, msg.ID, nil
+
+	pipe := c.Rdb.TxPipeline()
+	pipe.XAck(ctx, c.streamKey(t.Queue), c.Cfg.Group, streamID)
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: c.streamKey(t.Queue),
+		Values: encodeEntry(entry{TaskID: t.ID}),
+	})
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
-func (c *Client) Ack(ctx context.Context, streamID string) error {
-	return c.Rdb.XAck(ctx, c.Cfg.StreamKey, c.Cfg.Group, streamID).Err()
+func (c *Client) MarkHandedBack(ctx context.Context, id string) error {
+	return c.Rdb.HSet(ctx, taskKey(id), hashHandedBack, "1").Err()
 }
 
 func (c *Client) Fail(ctx context.Context, streamID string, t domain.Task, err error) error {
 	t.Attempts++
-	_ = c.SaveState(ctx, t)
-	return nil
+	t.LastError = err.Error()
+	return c.saveState(ctx, t, domain.EventRetrying)
 }
 
 func (c *Client) ToDLQ(ctx context.Context, streamID string, t domain.Task, reason string) error {
-	b, _ := json.Marshal(struct {
-		domain.Task
-		Reason string `json:"reason"`
-	}{t, reason})
 	if err := c.Rdb.XAdd(ctx, &redis.XAddArgs{
 		Stream: c.Cfg.DLQStreamKey,
-		Values: map[string]interface{}{"task": b},
+		Values: encodeEntry(entry{TaskID: t.ID, Reason: reason}),
 	}).Err(); err != nil {
 		return err
 	}
 
-	_ = c.Rdb.XAck(ctx, c.Cfg.StreamKey, c.Cfg.Group, streamID).Err()
+	_ = c.Rdb.XAck(ctx, c.streamKey(t.Queue), c.Cfg.Group, streamID).Err()
 	t.Status = domain.StatusFailed
+	t.LastError = reason
 	return c.SaveState(ctx, t)
 }
 
 func (c *Client) SaveState(ctx context.Context, t domain.Task) error {
+	return c.saveState(ctx, t, string(t.Status))
+}
+
+// saveState writes t and publishes event for it in one transaction.
+func (c *Client) saveState(ctx context.Context, t domain.Task, event string) error {
 	b, _ := json.Marshal(t)
 	log.Ctx(ctx).Info().RawJSON("task", b).Msg("saving task state")
-	key := fmt.Sprintf("task:%s", t.ID)
-	m := map[string]any{
-		"status":       string(t.Status),
-		"attempts":     t.Attempts,
-		"max_attempts": t.MaxAttempts,
-		"type":         t.Type,
-		"next_run_at":  t.NextRunAt.UnixMilli(),
-	}
-	for k, v := range t.Payload {
-		m["payload:"+k] = v
-	}
-	return c.Rdb.HSet(ctx, key, m).Err()
+	pipe := c.Rdb.TxPipeline()
+	pipe.HSet(ctx, taskKey(t.ID), encodeTask(t))
+	if t.Status.Final() {
+		// settled either way, e.g. a DLQ requeue starts afresh
+		pipe.HDel(ctx, taskKey(t.ID), hashCancel)
+	}
+	if !t.HandedBack {
+		pipe.HDel(ctx, taskKey(t.ID), hashHandedBack)
+	}
+	indexTask(ctx, pipe, t)
+	c.releaseUnique(ctx, pipe, t)
+	c.publishEvent(ctx, pipe, t, event)
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
 func (c *Client) Get(ctx context.Context, id string) (*domain.Task, error) {
-	key := fmt.Sprintf("task:%s", id)
-	h, err := c.Rdb.HGetAll(ctx, key).Result()
+	h, err := c.Rdb.HGetAll(ctx, taskKey(id)).Result()
 	if err != nil || len(h) == 0 {
 		return nil, err
 	}
-
-	t := &domain.Task{
-		ID:      id,
-		Payload: map[string]string{},
-	}
-	t.Type = h["type"]
-	t.Status = domain.TaskStatus(h["status"])
-
-	for k, v := range h {
-		if len(k) > 8 && k[:8] == "payload:" {
-			t.Payload[k[8:]] = v
-		}
-	}
-	return t, nil
+	return decodeTask(id, h)
 }
@@ -0,0 +1,169 @@
+package redisq
+
+import (
+	"encoding/json"
+	"redisq/internal/domain"
+	"redisq/pkg/backoff"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Task state lives in the task:<id> hash. encodeTask and decodeTask are the
+// only places that know its layout, so every domain.Task field round-trips.
+//
+// Payloads are stored as raw JSON in the "payload" field. Hashes written by
+// older versions flattened a map[string]string into "payload:<key>" fields;
+// those are still read and turned back into a JSON object.
+const (
+	hashType        = "type"
+	hashQueue       = "queue"
+	hashStatus      = "status"
+	hashAttempts    = "attempts"
+	hashMaxAttempts = "max_attempts"
+	hashCreatedAt   = "created_at"
+	hashNextRunAt   = "next_run_at"
+	hashPayload     = "payload"
+	hashTimeout     = "timeout_ms"
+	hashDeadline    = "deadline"
+	hashLastError   = "last_error"
+	hashRetry       = "retry"
+	hashHistory     = "history"
+	hashTrace       = "trace"
+	hashUnique      = "unique"
+	hashUniqueKey   = "unique_key"
+	hashCancel      = "cancel_requested"
+	hashHandedBack  = "handed_back"
+
+	legacyPayloadPrefix = "payload:"
+)
+
+func taskKey(id string) string { return "task:" + id }
+
+func encodeTask(t domain.Task) map[string]any {
+	m := map[string]any{
+		hashStatus:      string(t.Status),
+		hashAttempts:    t.Attempts,
+		hashMaxAttempts: t.MaxAttempts,
+		hashType:        t.Type,
+		hashQueue:       t.Queue,
+		hashCreatedAt:   unixMs(t.CreatedAt),
+		hashNextRunAt:   unixMs(t.NextRunAt),
+		hashTimeout:     t.Timeout.Milliseconds(),
+		hashDeadline:    unixMs(t.Deadline),
+		hashLastError:   t.LastError,
+	}
+	if len(t.Payload) > 0 {
+		m[hashPayload] = string(t.Payload)
+	}
+	if t.Retry != nil {
+		b, _ := json.Marshal(t.Retry)
+		m[hashRetry] = string(b)
+	}
+	if len(t.History) > 0 {
+		b, _ := json.Marshal(t.History)
+		m[hashHistory] = string(b)
+	}
+	if len(t.Trace) > 0 {
+		b, _ := json.Marshal(t.Trace)
+		m[hashTrace] = string(b)
+	}
+	if t.Unique != nil {
+		b, _ := json.Marshal(t.Unique)
+		m[hashUnique] = string(b)
+		m[hashUniqueKey] = t.UniqueKey
+	}
+	// only ever set, so a stale copy of the task can't clear a request
+	// made by the cancel script meanwhile
+	if t.CancelRequested {
+		m[hashCancel] = "1"
+	}
+	if t.HandedBack {
+		m[hashHandedBack] = "1"
+	}
+	return m
+}
+
+func decodeTask(id string, h map[string]string) (*domain.Task, error) {
+	t := &domain.Task{
+		ID:        id,
+		Type:      h[hashType],
+		Queue:     h[hashQueue],
+		Status:    domain.TaskStatus(h[hashStatus]),
+		LastError: h[hashLastError],
+		UniqueKey: h[hashUniqueKey],
+
+		CancelRequested: h[hashCancel] == "1",
+		HandedBack:      h[hashHandedBack] == "1",
+	}
+	t.Attempts, _ = strconv.Atoi(h[hashAttempts])
+	t.MaxAttempts, _ = strconv.Atoi(h[hashMaxAttempts])
+	t.CreatedAt = fromUnixMs(h[hashCreatedAt])
+	t.NextRunAt = fromUnixMs(h[hashNextRunAt])
+	t.Deadline = fromUnixMs(h[hashDeadline])
+	if ms, err := strconv.ParseInt(h[hashTimeout], 10, 64); err == nil {
+		t.Timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	if raw, ok := h[hashRetry]; ok && raw != "" {
+		t.Retry = &backoff.Spec{}
+		if err := json.Unmarshal([]byte(raw), t.Retry); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := h[hashHistory]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &t.History); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := h[hashUnique]; ok && raw != "" {
+		t.Unique = &domain.UniqueSpec{}
+		if err := json.Unmarshal([]byte(raw), t.Unique); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := h[hashTrace]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &t.Trace); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := h[hashPayload]; ok {
+		t.Payload = json.RawMessage(raw)
+		return t, nil
+	}
+
+	legacy := map[string]string{}
+	for k, v := range h {
+		if strings.HasPrefix(k, legacyPayloadPrefix) {
+			legacy[k[len(legacyPayloadPrefix):]] = v
+		}
+	}
+	if len(legacy) > 0 {
+		b, err := json.Marshal(legacy)
+		if err != nil {
+			return nil, err
+		}
+		t.Payload = b
+	}
+	return t, nil
+}
+
+// unixMs stores the zero time as 0 rather than a large negative number.
+func unixMs(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func fromUnixMs(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
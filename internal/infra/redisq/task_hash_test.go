@@ -0,0 +1,139 @@
+package redisq
+
+import (
+	"encoding/json"
+	"redisq/internal/domain"
+	"redisq/pkg/backoff"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeTask(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    map[string]string
+		want    domain.Task
+		wantErr bool
+	}{
+		{
+			name: "current layout",
+			hash: map[string]string{
+				"type":         "email.send",
+				"queue":        "critical",
+				"status":       "delayed",
+				"attempts":     "2",
+				"max_attempts": "5",
+				"created_at":   "1700000000000",
+				"next_run_at":  "1700000060000",
+				"timeout_ms":   "1500",
+				"payload":      `{"to":"a@b.c"}`,
+				"last_error":   "boom",
+			},
+			want: domain.Task{
+				ID:          "t1",
+				Type:        "email.send",
+				Queue:       "critical",
+				Status:      domain.StatusDelayed,
+				Attempts:    2,
+				MaxAttempts: 5,
+				CreatedAt:   time.UnixMilli(1700000000000),
+				NextRunAt:   time.UnixMilli(1700000060000),
+				Timeout:     1500 * time.Millisecond,
+				Payload:     json.RawMessage(`{"to":"a@b.c"}`),
+				LastError:   "boom",
+			},
+		},
+		{
+			name: "legacy flattened payload",
+			hash: map[string]string{
+				"type":       "email.send",
+				"status":     "queued",
+				"payload:to": "a@b.c",
+				"payload:n":  "1",
+			},
+			want: domain.Task{
+				ID:      "t1",
+				Type:    "email.send",
+				Status:  domain.StatusQueued,
+				Payload: json.RawMessage(`{"n":"1","to":"a@b.c"}`),
+			},
+		},
+		{
+			name: "raw payload wins over legacy fields",
+			hash: map[string]string{
+				"payload":    `[1,2]`,
+				"payload:to": "a@b.c",
+			},
+			want: domain.Task{ID: "t1", Payload: json.RawMessage(`[1,2]`)},
+		},
+		{
+			name: "legacy hash without counters or times",
+			hash: map[string]string{"type": "x", "status": "running"},
+			want: domain.Task{ID: "t1", Type: "x", Status: domain.StatusRunning},
+		},
+		{
+			name:    "invalid retry spec",
+			hash:    map[string]string{"retry": `{`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeTask("t1", tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("decodeTask() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskHashRoundTrip(t *testing.T) {
+	in := domain.Task{
+		ID:          "t1",
+		Type:        "email.send",
+		Queue:       "low",
+		Payload:     json.RawMessage(`{"to":"a@b.c"}`),
+		Attempts:    1,
+		MaxAttempts: 3,
+		Status:      domain.StatusFailed,
+		CreatedAt:   time.UnixMilli(1700000000000),
+		NextRunAt:   time.UnixMilli(1700000001000),
+		Timeout:     time.Second,
+		Deadline:    time.UnixMilli(1700000100000),
+		Retry:       &backoff.Spec{Policy: "fibonacci", BaseMs: 500},
+		LastError:   "boom",
+		History:     []domain.HistoryEntry{{At: time.UnixMilli(1690000000000), Status: domain.StatusFailed, Attempts: 3, Error: "earlier"}},
+		Trace:       map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+		Unique:      &domain.UniqueSpec{Fields: []string{"to"}, TTLMs: 1000},
+		UniqueKey:   "email.send:abc",
+	}
+
+	h := map[string]string{}
+	for k, v := range encodeTask(in) {
+		b, _ := json.Marshal(v)
+		if s, ok := v.(string); ok {
+			h[k] = s
+			continue
+		}
+		h[k] = string(b)
+	}
+
+	got, err := decodeTask(in.ID, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// compared as JSON: decoded times carry a different *time.Location
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(in)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("round trip = %s, want %s", gotJSON, wantJSON)
+	}
+}
@@ -0,0 +1,39 @@
+package redisq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+)
+
+var _ ports.TypeStore = (*Client)(nil)
+
+// TypeConfigs reads every task type config from the TypesKey hash
+// (field = type, value = JSON TypeConfig).
+func (c *Client) TypeConfigs(ctx context.Context) ([]domain.TypeConfig, error) {
+	h, err := c.Rdb.HGetAll(ctx, c.Cfg.TypesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.TypeConfig, 0, len(h))
+	for typ, raw := range h {
+		var tc domain.TypeConfig
+		if err := json.Unmarshal([]byte(raw), &tc); err != nil {
+			return nil, fmt.Errorf("invalid type config for %s: %w", typ, err)
+		}
+		tc.Type = typ
+		out = append(out, tc)
+	}
+	return out, nil
+}
+
+func (c *Client) SaveTypeConfig(ctx context.Context, tc domain.TypeConfig) error {
+	b, err := json.Marshal(tc)
+	if err != nil {
+		return err
+	}
+	return c.Rdb.HSet(ctx, c.Cfg.TypesKey, tc.Type, b).Err()
+}
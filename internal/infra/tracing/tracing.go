@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Setup installs the W3C trace context propagator and an SDK tracer
+// provider, so spans get real trace IDs that travel with tasks and show up in
+// the logs. When an OTLP endpoint is configured through the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT
+// variables, spans are exported to it over OTLP/HTTP; without one they are
+// dropped once ended.
+//
+// The returned func flushes pending spans and must be called on exit.
+func Setup(ctx context.Context, service string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override service
+	res, err := resource.Merge(
+		resource.NewSchemaless(semconv.ServiceName(service)),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+
+	export := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+	if export {
+		exp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+
+	if export {
+		log.Ctx(ctx).Info().Str("service", service).Msg("exporting traces over OTLP")
+	}
+	return tp.Shutdown, nil
+}
@@ -6,18 +6,165 @@ import (
 	"time"
 )
 
+// Delivery is a claimed task together with the stream entry it was read from.
+type Delivery struct {
+	StreamID string
+	Task     domain.Task
+}
+
 type Queue interface {
+	// Enqueue and EnqueueDelayed return the task ID
 	Enqueue(ctx context.Context, t domain.Task) (string, error)
 	EnqueueDelayed(ctx context.Context, t domain.Task, runAt time.Time) (string, error)
-	Claim(ctx context.Context, consumer string, block time.Duration) (*domain.Task, string /*streamID*/, error)
-	Ack(ctx context.Context, streamID string) error
+	// enqueues t (delayed when runAt is set) if g lets it; a repeated
+	// idempotency key returns the ID of the task first enqueued under it
+	EnqueueGuarded(ctx context.Context, t domain.Task, runAt time.Time, g Guard) (id string, dup bool, err error)
+	// claims from queues in the given order, see redisq.Client.Claim
+	Claim(ctx context.Context, consumer string, queues []string, count int, block time.Duration) ([]Delivery, error)
+	Ack(ctx context.Context, queue, streamID string) error
+	// resets the idle time of consumer's pending entries, marking them as
+	// still being worked on
+	Touch(ctx context.Context, consumer, queue string, streamIDs ...string) error
+	Requeue(ctx context.Context, streamID string, t domain.Task) error
+	// flags a task left pending at shutdown, see domain.Task.HandedBack
+	MarkHandedBack(ctx context.Context, id string) error
 	Fail(ctx context.Context, streamID string, t domain.Task, err error) error
 	ToDLQ(ctx context.Context, streamID string, t domain.Task, reason string) error
 	SaveState(ctx context.Context, t domain.Task) error
 	Get(ctx context.Context, id string) (*domain.Task, error)
 }
 
+// Guard makes an enqueue conditional. Zero fields don't apply.
+type Guard struct {
+	// IdempotencyKey, repeated within IdempotencyWindow, returns the
+	// original task instead of enqueueing again.
+	IdempotencyKey    string
+	IdempotencyWindow time.Duration
+	// UniqueTTL caps how long the task's uniqueness lock (on its UniqueKey)
+	// is held if it is never released.
+	UniqueTTL time.Duration
+}
+
+type ResultStore interface {
+	// stores r for ttl (zero stores nothing), replacing any earlier result
+	// of the task, and wakes its waiters either way
+	SaveResult(ctx context.Context, r domain.TaskResult, ttl time.Duration) error
+	// returns nil if there is no result (yet, or any more)
+	Result(ctx context.Context, id string) (*domain.TaskResult, error)
+	// blocks until the task's result is stored, or ctx is done; a result
+	// stored earlier is returned at once
+	WaitResult(ctx context.Context, id string) (*domain.TaskResult, error)
+}
+
+type EventStream interface {
+	// streams lifecycle events of tasks of type typ ("" = every type),
+	// starting once it returns; the channel is closed when ctx is done
+	Events(ctx context.Context, typ string) (<-chan domain.TaskEvent, error)
+}
+
 type Scheduler interface {
 	// moves due tasks from ZSET into the stream
 	Run(ctx context.Context) error
 }
+
+type Reaper interface {
+	// reclaims entries idle in the pending list past the visibility timeout
+	Run(ctx context.Context) error
+}
+
+// TypeResolver looks up a task type's config; unknown types get one with
+// just the type set.
+type TypeResolver interface {
+	Lookup(ctx context.Context, taskType string) domain.TypeConfig
+}
+
+type TypeStore interface {
+	TypeConfigs(ctx context.Context) ([]domain.TypeConfig, error)
+	SaveTypeConfig(ctx context.Context, tc domain.TypeConfig) error
+}
+
+// TaskFilter selects tasks by status and/or type, newest first.
+type TaskFilter struct {
+	Status domain.TaskStatus
+	Type   string
+	Offset int64
+	Limit  int64
+}
+
+type TaskLister interface {
+	// returns the requested page and the total number of matching tasks
+	List(ctx context.Context, f TaskFilter) ([]domain.Task, int64, error)
+}
+
+// DLQEntry is one dead-lettered task. Task is nil if its state is gone.
+type DLQEntry struct {
+	StreamID string       `json:"stream_id"`
+	TaskID   string       `json:"task_id"`
+	Reason   string       `json:"reason"`
+	At       time.Time    `json:"at"`
+	Task     *domain.Task `json:"task,omitempty"`
+}
+
+type DLQ interface {
+	// pages through the DLQ oldest first, starting after cursor ("" = start)
+	DLQList(ctx context.Context, cursor string, count int64) ([]DLQEntry, string /*next cursor*/, error)
+	DLQGet(ctx context.Context, streamID string) (*DLQEntry, error)
+	DLQDelete(ctx context.Context, streamIDs ...string) error
+	DLQPurge(ctx context.Context) (int64, error)
+}
+
+type Canceller interface {
+	// cancels a queued or delayed task outright, or signals the worker
+	// running it; returns the task's status after the request
+	Cancel(ctx context.Context, id string) (domain.TaskStatus, error)
+	// streams the IDs of running tasks whose cancellation was requested;
+	// the channel is closed when ctx is done
+	CancelRequests(ctx context.Context) <-chan string
+}
+
+type PeriodicStore interface {
+	Periodics(ctx context.Context) ([]domain.Periodic, error)
+	SavePeriodic(ctx context.Context, p domain.Periodic) error
+	DeletePeriodic(ctx context.Context, name string) error
+	LastFired(ctx context.Context, name string) (time.Time, error)
+	// moves name's last fired time from prev to at, unless another instance
+	// already moved it; reports whether this caller won
+	AdvanceFired(ctx context.Context, name string, prev, at time.Time) (bool, error)
+}
+
+type Lease interface {
+	// acquires the lease for owner, or renews it if owner already holds it;
+	// token is the fencing token of the current term, 0 when not held
+	Acquire(ctx context.Context, owner string, ttl time.Duration) (token int64, err error)
+	Release(ctx context.Context, owner string) error
+	// reports whether token is still the current term's fencing token
+	Valid(ctx context.Context, token int64) (bool, error)
+}
+
+// Metrics receives task lifecycle events. Implementations must be safe for
+// concurrent use.
+type Metrics interface {
+	Enqueued(t domain.Task)
+	// one handler attempt finished, err is its outcome
+	Attempted(t domain.Task, d time.Duration, err error)
+	Retried(t domain.Task)
+	DeadLettered(t domain.Task)
+}
+
+// QueueStats is a point-in-time view of one queue's stream.
+type QueueStats struct {
+	Queue   string
+	Length  int64 // entries in the stream
+	Pending int64 // delivered to a consumer but not acked
+	Lag     int64 // not yet delivered to the group, -1 if unknown
+}
+
+type Stats struct {
+	Queues      []QueueStats
+	Scheduled   int64 // delayed tasks in the scheduled ZSET
+	DeadLetters int64 // entries in the DLQ stream
+}
+
+type StatsReader interface {
+	Stats(ctx context.Context) (Stats, error)
+}
@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"redisq/internal/domain"
+	"time"
+)
+
+// ErrWaitTimeout is returned by Wait and Call when the task has not finished
+// within the timeout. The task itself carries on.
+var ErrWaitTimeout = errors.New("timed out waiting for task result")
+
+// Call enqueues t and waits up to timeout for its result, for callers that
+// want request/response semantics over the queue. The task ID is returned
+// even when the wait times out, so the result can be fetched later; that
+// needs workers running with a result TTL, while waiting works without one.
+func (e Enqueuer) Call(ctx context.Context, t domain.Task, timeout time.Duration) (string, *domain.TaskResult, error) {
+	id, err := e.Now(ctx, t)
+	if err != nil {
+		return "", nil, err
+	}
+	res, err := e.Wait(ctx, id, timeout)
+	return id, res, err
+}
+
+// Wait blocks until the task's result is stored (done, failed, discarded or
+// cancelled) or timeout passes. It is woken by the worker storing the result rather
+// than polling.
+func (e Enqueuer) Wait(ctx context.Context, id string, timeout time.Duration) (*domain.TaskResult, error) {
+	if e.Results == nil {
+		return nil, errors.New("enqueuer has no result store")
+	}
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	res, err := e.Results.WaitResult(wctx, id)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return nil, ErrWaitTimeout
+	}
+	return res, err
+}
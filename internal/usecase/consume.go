@@ -1,68 +1,517 @@
 package usecase
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"redisq/internal/domain"
 	"redisq/internal/ports"
 	"redisq/pkg/backoff"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type Handler func(ctx context.Context, t domain.Task) error
+// Handler processes one task. The result, if any, is stored for producers
+// to fetch (see Consumer.Results); domain.NewPayload builds one from a value.
+type Handler func(ctx context.Context, t domain.Task) (json.RawMessage, error)
+
+var (
+	// ErrTimeout is recorded when an attempt runs past the task's Timeout.
+	// It is retried like any other failure.
+	ErrTimeout = errors.New("task attempt timed out")
+	// ErrDeadlineExceeded is recorded when the task's absolute Deadline
+	// passes. No later attempt could succeed, so the task is dead-lettered.
+	ErrDeadlineExceeded = errors.New("task deadline exceeded")
+	// ErrShutdown cancels handlers still running when the shutdown grace
+	// period ends. Their tasks are handed back without using up an attempt.
+	ErrShutdown = errors.New("worker shutting down")
+	// ErrCancelled cancels a running handler whose task was cancelled.
+	ErrCancelled = errors.New("task cancelled")
+)
+
+// DefaultAbortGrace applies when Consumer.AbortGrace is zero.
+const DefaultAbortGrace = 10 * time.Second
+
+// HandBack decides what happens to tasks still in flight when the shutdown
+// grace period ends.
+type HandBack int
+
+const (
+	// HandBackRequeue acks the entry and appends the task to the stream
+	// again, so another worker picks it up right away.
+	HandBackRequeue HandBack = iota
+	// HandBackPending leaves the entry in the pending list for the reaper,
+	// which requeues it after the visibility timeout. The task is marked
+	// handed back, so that doesn't count as an attempt either.
+	HandBackPending
+)
 
 type Consumer struct {
 	Q            ports.Queue
 	ConsumerName string
-	BaseBackoff  time.Duration
-	MaxBackoff   time.Duration
+	// Types supplies per-type timeouts, retry policies and exhaustion
+	// behaviour; they take precedence over the flag-based defaults below.
+	Types *TypeRegistry
+	// BaseBackoff and MaxBackoff configure the default exponential retry
+	// policy, used when neither the task nor TypeRetry names one.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	TypeRetry   map[string]backoff.RetryPolicy
+	// Concurrency is the number of tasks processed at once. Zero means 1.
+	Concurrency int
+	// DefaultTimeout and TypeTimeouts apply to tasks enqueued without a Timeout.
+	DefaultTimeout time.Duration
+	TypeTimeouts   map[string]time.Duration
+	// ShutdownGrace is how long in-flight tasks may keep running after ctx
+	// is cancelled before they are aborted and handed back.
+	ShutdownGrace time.Duration
+	HandBack      HandBack
+	// Cancels, when set, delivers cancellation requests for running tasks.
+	Cancels ports.Canceller
+	// Queues lists the queues to consume, highest priority first; empty
+	// means just the default queue. QueueMode picks between them.
+	Queues    []QueueWeight
+	QueueMode QueueMode
+	// Metrics, when set, counts retries and dead-lettered tasks. Attempt
+	// latency is left to the Timer middleware.
+	Metrics ports.Metrics
+	// Results, when set, keeps the outcome of finished tasks for ResultTTL
+	// and wakes callers waiting on it; with a zero ResultTTL it only wakes
+	// them.
+	Results   ports.ResultStore
+	ResultTTL time.Duration
+	// AbortGrace is how long a handler whose attempt timed out or was
+	// cancelled may take to return before its slot is freed anyway. Zero
+	// means DefaultAbortGrace.
+	AbortGrace time.Duration
+	// Heartbeat is how often the stream entries of running tasks are
+	// touched, so the reaper doesn't take them for abandoned however long
+	// the handler runs. It must be well below the reaper's visibility
+	// timeout; zero disables it.
+	Heartbeat time.Duration
+}
+
+// inflight tracks running tasks by ID: their cancel funcs and the stream
+// entries to keep alive.
+type inflight struct {
+	mu    sync.Mutex
+	tasks map[string]inflightTask
+}
+
+type inflightTask struct {
+	queue    string
+	streamID string
+	cancel   context.CancelCauseFunc
+}
+
+func (f *inflight) add(d ports.Delivery, cancel context.CancelCauseFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tasks[d.Task.ID] = inflightTask{queue: d.Task.Queue, streamID: d.StreamID, cancel: cancel}
+}
+
+func (f *inflight) remove(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.tasks, id)
 }
 
+func (f *inflight) cancel(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.tasks[id]
+	if ok {
+		t.cancel(ErrCancelled)
+	}
+	return ok
+}
+
+// entries returns the stream IDs of running tasks by queue.
+func (f *inflight) entries() map[string][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := map[string][]string{}
+	for _, t := range f.tasks {
+		out[t.queue] = append(out[t.queue], t.streamID)
+	}
+	return out
+}
+
+// Run claims tasks in batches sized to the free worker slots and processes
+// each one on its own goroutine. When every slot is busy it stops claiming
+// until one frees up.
+//
+// Cancelling ctx starts a two-phase shutdown: claiming stops at once, while
+// handlers keep their context until ShutdownGrace runs out. Whatever is still
+// running then is aborted with ErrShutdown and handed back.
 func (c Consumer) Run(ctx context.Context, handle Handler) error {
+	n := max(c.Concurrency, 1)
+	slots := make(chan struct{}, n)
+	var wg sync.WaitGroup
+
+	// Handlers and Redis bookkeeping must outlive ctx during the drain.
+	base := context.WithoutCancel(ctx)
+	work, abort := context.WithCancelCause(base)
+	defer abort(nil)
+
+	running := &inflight{tasks: map[string]inflightTask{}}
+	picker := newQueuePicker(c.QueueMode, c.Queues)
+	if c.Heartbeat > 0 {
+		go c.heartbeat(work, running)
+	}
+	if c.Cancels != nil {
+		go func() {
+			for id := range c.Cancels.CancelRequests(work) {
+				if running.cancel(id) {
+					log.Ctx(ctx).Info().Str("task_id", id).Msg("cancelling running task")
+				}
+			}
+		}()
+	}
+
 	for {
+		// block until at least one slot is free
 		select {
 		case <-ctx.Done():
+			c.drain(ctx, &wg, abort)
 			return ctx.Err()
-		default:
+		case slots <- struct{}{}:
 		}
 
-		t, id, err := c.Q.Claim(ctx, c.ConsumerName, 5*time.Second)
-		log.Ctx(ctx).Info().Msgf("claimed task: %v, id: %s, err: %v", t, id, err)
+		// grab whatever else is free to size the batch
+		free := 1
+	fill:
+		for free < n {
+			select {
+			case slots <- struct{}{}:
+				free++
+			default:
+				break fill
+			}
+		}
+
+		ds, err := c.Q.Claim(ctx, c.ConsumerName, picker.next(), free, 5*time.Second)
 		if err != nil {
 			log.Ctx(ctx).Error().Err(err).Msg("claim failed")
-			continue
 		}
-		if t == nil {
+
+		// hand back the slots this batch didn't fill
+		for range free - len(ds) {
+			<-slots
+		}
+
+		if len(ds) == 0 {
 			log.Ctx(ctx).Debug().Msg("no task claimed")
 			continue
 		}
 
-		// Mark running
-		t.Status = domain.StatusRunning
-		_ = c.Q.SaveState(ctx, *t)
+		for _, d := range ds {
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				c.process(base, work, running, d, handle)
+			}()
+		}
+	}
+}
 
-		err = handle(ctx, *t)
-		if err == nil {
-			_ = c.Q.Ack(ctx, id)
-			t.Status = domain.StatusDone
-			_ = c.Q.SaveState(ctx, *t)
-			continue
+// heartbeat touches the entries of running tasks every Heartbeat until ctx
+// is done.
+func (c Consumer) heartbeat(ctx context.Context, running *inflight) {
+	ticker := time.NewTicker(c.Heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for queue, ids := range running.entries() {
+			if err := c.Q.Touch(ctx, c.ConsumerName, queue, ids...); err != nil {
+				log.Ctx(ctx).Error().Err(err).Str("queue", queue).Msg("failed to touch in-flight tasks")
+			}
 		}
+	}
+}
+
+// drain waits for in-flight tasks, aborting them once the grace period ends.
+func (c Consumer) drain(ctx context.Context, wg *sync.WaitGroup, abort context.CancelCauseFunc) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	log.Info().Dur("grace", c.ShutdownGrace).Msg("consumer stopped claiming, draining in-flight tasks")
+	timer := time.NewTimer(c.ShutdownGrace)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		log.Info().Msg("in-flight tasks drained")
+		return
+	case <-timer.C:
+	}
+
+	log.Warn().Msg("shutdown grace period expired, handing back in-flight tasks")
+	abort(ErrShutdown)
+	<-done
+}
+
+// process runs one delivery. Handlers get work, which is only cancelled when
+// the shutdown grace period expires; bookkeeping uses ctx, which never is.
+func (c Consumer) process(ctx, work context.Context, running *inflight, d ports.Delivery, handle Handler) {
+	t, id := d.Task, d.StreamID
+
+	// Register before re-reading the status: a cancel that lands in between
+	// is then either seen here or delivered to the registered func.
+	work, cancel := context.WithCancelCause(work)
+	defer cancel(nil)
+	running.add(d, cancel)
+	defer running.remove(t.ID)
+
+	if cur, err := c.Q.Get(ctx, t.ID); err == nil && cur != nil {
+		switch {
+		case cur.Status.Final():
+			log.Ctx(ctx).Info().Str("task_id", t.ID).Str("status", string(cur.Status)).Msg("skipping finished task")
+			_ = c.Q.Ack(ctx, t.Queue, id)
+			return
+		case cur.CancelRequested:
+			c.cancelled(ctx, id, t)
+			return
+		}
+	}
+	if t.Attempts >= maxAttempts(ctx, c.Types, t) {
+		// out of attempts before this one, e.g. after the reaper counted
+		// deliveries lost with their workers
+		c.giveUp(ctx, id, t, errors.New(cmp.Or(t.LastError, "max attempts reached")), true)
+		return
+	}
 
-		// Failure path: retry or DLQ
-		if t.Attempts+1 >= t.MaxAttempts {
-			_ = c.Q.ToDLQ(ctx, id, *t, err.Error())
+	// The attempt span covers the handler and the bookkeeping after it
+	ctx, span := startAttempt(ctx, t)
+	work = trace.ContextWithSpan(work, span)
+
+	// Mark running
+	t.Status = domain.StatusRunning
+	_ = c.Q.SaveState(ctx, t)
+
+	res, err := c.execute(work, t, handle)
+	defer endSpan(span, err)
+	if err != nil {
+		switch cause := context.Cause(work); {
+		case errors.Is(cause, ErrShutdown):
+			c.handBack(ctx, id, t)
+			return
+		case errors.Is(cause, ErrCancelled):
+			c.cancelled(ctx, id, t)
+			return
+		}
+	}
+	if err == nil {
+		_ = c.Q.Ack(ctx, t.Queue, id)
+		t.Status = domain.StatusDone
+		c.saveResult(ctx, t, res)
+		_ = c.Q.SaveState(ctx, t)
+		return
+	}
+
+	if errors.Is(err, Discard) {
+		c.discard(ctx, id, t, err)
+		return
+	}
+
+	// Failure path: retry or DLQ
+	exhausted := t.Attempts+1 >= maxAttempts(ctx, c.Types, t)
+	if errors.Is(err, SkipRetry) || errors.Is(err, ErrDeadlineExceeded) || exhausted {
+		t.Attempts++
+		c.giveUp(ctx, id, t, err, exhausted)
+		return
+	}
+
+	// compute backoff and reschedule by adding back to scheduled ZSET via SaveState + ZADD
+	delay, ok := retryDelay(err)
+	if !ok {
+		delay = c.retryPolicy(t, c.Types.Lookup(ctx, t.Type)).Delay(t.Attempts + 1)
+	}
+	t.NextRunAt = time.Now().Add(delay)
+	_ = c.Q.Fail(ctx, id, t, err)
+	t.Attempts++
+	t.LastError = err.Error()
+
+	// remove from PEL by acking and then re-inserting as delayed
+	_ = c.Q.Ack(ctx, t.Queue, id)
+	_, _ = c.Q.EnqueueDelayed(ctx, t, t.NextRunAt)
+	if c.Metrics != nil {
+		c.Metrics.Retried(t)
+	}
+}
+
+// giveUp ends a task that gets no further attempt: discarded if it ran out
+// of attempts and its type says so, dead-lettered otherwise.
+func (c Consumer) giveUp(ctx context.Context, id string, t domain.Task, err error, exhausted bool) {
+	if exhausted && c.Types.Lookup(ctx, t.Type).OnExhausted == domain.OnExhaustedDiscard {
+		c.discard(ctx, id, t, err)
+		return
+	}
+	t.Status, t.LastError = domain.StatusFailed, err.Error()
+	c.saveResult(ctx, t, nil)
+	_ = c.Q.ToDLQ(ctx, id, t, err.Error())
+	if c.Metrics != nil {
+		c.Metrics.DeadLettered(t)
+	}
+}
+
+func (c Consumer) discard(ctx context.Context, id string, t domain.Task, err error) {
+	_ = c.Q.Ack(ctx, t.Queue, id)
+	t.Status = domain.StatusDiscarded
+	t.LastError = err.Error()
+	c.saveResult(ctx, t, nil)
+	_ = c.Q.SaveState(ctx, t)
+}
+
+// saveResult stores the outcome of a task that reached a final state: the
+// handler's result when done, the last error otherwise.
+func (c Consumer) saveResult(ctx context.Context, t domain.Task, res json.RawMessage) {
+	if c.Results == nil {
+		return
+	}
+	r := domain.TaskResult{
+		TaskID:     t.ID,
+		Status:     t.Status,
+		Result:     res,
+		Attempts:   t.Attempts,
+		FinishedAt: time.Now(),
+	}
+	if t.Status != domain.StatusDone {
+		r.Error = t.LastError
+	}
+	if err := c.Results.SaveResult(ctx, r, c.ResultTTL); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("task_id", t.ID).Msg("failed to store task result")
+	}
+}
+
+// cancelled acks a task whose cancellation was requested and marks it
+// cancelled, which also releases its uniqueness lock and wakes its waiters.
+func (c Consumer) cancelled(ctx context.Context, id string, t domain.Task) {
+	log.Ctx(ctx).Info().Str("task_id", t.ID).Msg("task cancelled")
+	_ = c.Q.Ack(ctx, t.Queue, id)
+	t.Status = domain.StatusCancelled
+	t.LastError = ErrCancelled.Error()
+	c.saveResult(ctx, t, nil)
+	_ = c.Q.SaveState(ctx, t)
+}
+
+// retryPolicy picks the task's own policy, then the registry's, then the
+// flag-based one for its type, then the default.
+func (c Consumer) retryPolicy(t domain.Task, tc domain.TypeConfig) backoff.RetryPolicy {
+	for _, spec := range []*backoff.Spec{t.Retry, tc.Retry} {
+		if spec == nil {
 			continue
 		}
+		p, err := spec.RetryPolicy()
+		if err == nil {
+			return p
+		}
+		log.Warn().Err(err).Str("task_id", t.ID).Msg("ignoring invalid retry policy")
+	}
+	if p, ok := c.TypeRetry[t.Type]; ok {
+		return p
+	}
+	return backoff.Exponential{Base: c.BaseBackoff, Max: c.MaxBackoff}
+}
+
+func (c Consumer) handBack(ctx context.Context, id string, t domain.Task) {
+	// a cancel that came in meanwhile wins over handing it back
+	if cur, err := c.Q.Get(ctx, t.ID); err == nil && cur != nil && cur.CancelRequested {
+		c.cancelled(ctx, id, t)
+		return
+	}
+	if c.HandBack == HandBackPending {
+		if err := c.Q.MarkHandedBack(ctx, t.ID); err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("task_id", t.ID).Msg("failed to mark in-flight task handed back")
+		}
+		log.Ctx(ctx).Warn().Str("task_id", t.ID).Msg("left in-flight task pending for the reaper")
+		return
+	}
+	if err := c.Q.Requeue(ctx, id, t); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("task_id", t.ID).Msg("failed to requeue in-flight task")
+		return
+	}
+	log.Ctx(ctx).Info().Str("task_id", t.ID).Msg("requeued in-flight task")
+}
 
-		// compute backoff and reschedule by adding back to scheduled ZSET via SaveState + ZADD
-		delay := backoff.ExponentialJitter(c.BaseBackoff, c.MaxBackoff, t.Attempts+1)
-		t.NextRunAt = time.Now().Add(delay)
-		_ = c.Q.Fail(ctx, id, *t, err)
+// execute runs handle under the task's timeout and deadline. If either
+// expires, or ctx is cancelled, the handler's context is cancelled and the
+// attempt fails. The handler still gets up to AbortGrace to return, so its
+// worker slot stays taken and a retry never overlaps it; a handler that
+// ignores its context past that is left to finish on its own.
+func (c Consumer) execute(ctx context.Context, t domain.Task, handle Handler) (json.RawMessage, error) {
+	timeout := t.Timeout
+	if timeout == 0 {
+		timeout = time.Duration(c.Types.Lookup(ctx, t.Type).TimeoutMs) * time.Millisecond
+	}
+	if timeout == 0 {
+		timeout = c.TypeTimeouts[t.Type]
+	}
+	if timeout == 0 {
+		timeout = c.DefaultTimeout
+	}
+
+	hctx := ctx
+	if !t.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		hctx, cancel = context.WithDeadlineCause(hctx, t.Deadline, ErrDeadlineExceeded)
+		defer cancel()
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		hctx, cancel = context.WithTimeoutCause(hctx, timeout, ErrTimeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		res json.RawMessage
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		res, err := handle(hctx, t)
+		done <- outcome{res, err}
+	}()
+
+	select {
+	case o := <-done:
+		if o.err != nil && hctx.Err() != nil && ctx.Err() == nil {
+			return nil, context.Cause(hctx)
+		}
+		return o.res, o.err
+	case <-hctx.Done():
+	}
+
+	grace := c.AbortGrace
+	if grace <= 0 {
+		grace = DefaultAbortGrace
+	}
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		log.Ctx(ctx).Warn().Str("task_id", t.ID).Dur("grace", grace).
+			Msg("handler ignored cancellation, freeing its slot while it still runs")
+	}
 
-		// remove from PEL by acking and then re-inserting as delayed
-		_ = c.Q.Ack(ctx, id)
-		_, _ = c.Q.EnqueueDelayed(ctx, *t, t.NextRunAt)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
 	}
+	return nil, context.Cause(hctx)
 }
@@ -0,0 +1,271 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"redisq/pkg/backoff"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeQueue records what the consumer does with a delivery.
+type fakeQueue struct {
+	mu      sync.Mutex
+	acked   []string
+	dlq     map[string]string // stream ID -> reason
+	delayed []domain.Task
+	state   *domain.Task // last task saved
+}
+
+func (q *fakeQueue) Enqueue(ctx context.Context, t domain.Task) (string, error) {
+	return t.ID, nil
+}
+
+func (q *fakeQueue) EnqueueDelayed(ctx context.Context, t domain.Task, runAt time.Time) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t.NextRunAt = runAt
+	q.delayed = append(q.delayed, t)
+	return t.ID, nil
+}
+
+func (q *fakeQueue) EnqueueGuarded(ctx context.Context, t domain.Task, runAt time.Time, g ports.Guard) (string, bool, error) {
+	return t.ID, false, nil
+}
+
+func (q *fakeQueue) Claim(ctx context.Context, consumer string, queues []string, count int, block time.Duration) ([]ports.Delivery, error) {
+	return nil, nil
+}
+
+func (q *fakeQueue) Ack(ctx context.Context, queue, streamID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.acked = append(q.acked, streamID)
+	return nil
+}
+
+func (q *fakeQueue) Touch(ctx context.Context, consumer, queue string, streamIDs ...string) error {
+	return nil
+}
+
+func (q *fakeQueue) Requeue(ctx context.Context, streamID string, t domain.Task) error { return nil }
+
+func (q *fakeQueue) MarkHandedBack(ctx context.Context, id string) error { return nil }
+
+func (q *fakeQueue) Fail(ctx context.Context, streamID string, t domain.Task, err error) error {
+	return nil
+}
+
+func (q *fakeQueue) ToDLQ(ctx context.Context, streamID string, t domain.Task, reason string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.dlq == nil {
+		q.dlq = map[string]string{}
+	}
+	q.dlq[streamID] = reason
+	q.state = &t
+	return nil
+}
+
+func (q *fakeQueue) SaveState(ctx context.Context, t domain.Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.state = &t
+	return nil
+}
+
+func (q *fakeQueue) Get(ctx context.Context, id string) (*domain.Task, error) {
+	return nil, nil
+}
+
+func TestConsumerProcessFailures(t *testing.T) {
+	const streamID = "1-0"
+	fail := func(err error) Handler {
+		return func(context.Context, domain.Task) (json.RawMessage, error) { return nil, err }
+	}
+	block := func(ctx context.Context, _ domain.Task) (json.RawMessage, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	constant := &backoff.Spec{Policy: "constant", BaseMs: 2000}
+
+	tests := []struct {
+		name   string
+		task   domain.Task
+		types  []domain.TypeConfig
+		handle Handler
+
+		wantStatus   domain.TaskStatus
+		wantDLQ      bool
+		wantRetry    time.Duration // delay of the retry, when one is scheduled
+		wantAttempts int
+		wantErr      error // matched against the recorded LastError
+	}{
+		{
+			name:         "success",
+			task:         domain.Task{MaxAttempts: 3},
+			handle:       fail(nil),
+			wantStatus:   domain.StatusDone,
+			wantAttempts: 0,
+		},
+		{
+			name:         "error is retried with the task's policy",
+			task:         domain.Task{MaxAttempts: 3, Retry: constant},
+			handle:       fail(errors.New("boom")),
+			wantRetry:    2 * time.Second,
+			wantAttempts: 1,
+		},
+		{
+			name:         "RetryAfter overrides the policy",
+			task:         domain.Task{MaxAttempts: 3, Retry: constant},
+			handle:       fail(RetryAfter(time.Minute, errors.New("rate limited"))),
+			wantRetry:    time.Minute,
+			wantAttempts: 1,
+		},
+		{
+			name:         "SkipRetry is dead-lettered",
+			task:         domain.Task{MaxAttempts: 3},
+			handle:       fail(fmt.Errorf("bad payload: %w", SkipRetry)),
+			wantStatus:   domain.StatusFailed,
+			wantDLQ:      true,
+			wantAttempts: 1,
+			wantErr:      SkipRetry,
+		},
+		{
+			name:         "SkipRetry ignores on_exhausted",
+			task:         domain.Task{Type: "quiet", MaxAttempts: 3},
+			types:        []domain.TypeConfig{{Type: "quiet", OnExhausted: domain.OnExhaustedDiscard}},
+			handle:       fail(SkipRetry),
+			wantStatus:   domain.StatusFailed,
+			wantDLQ:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Discard drops the task",
+			task:         domain.Task{MaxAttempts: 3},
+			handle:       fail(fmt.Errorf("stale: %w", Discard)),
+			wantStatus:   domain.StatusDiscarded,
+			wantAttempts: 0,
+			wantErr:      Discard,
+		},
+		{
+			name:         "exhausted is dead-lettered",
+			task:         domain.Task{MaxAttempts: 3, Attempts: 2},
+			handle:       fail(errors.New("boom")),
+			wantStatus:   domain.StatusFailed,
+			wantDLQ:      true,
+			wantAttempts: 3,
+		},
+		{
+			name:         "exhausted with on_exhausted discard",
+			task:         domain.Task{Type: "quiet", MaxAttempts: 3, Attempts: 2},
+			types:        []domain.TypeConfig{{Type: "quiet", OnExhausted: domain.OnExhaustedDiscard}},
+			handle:       fail(errors.New("boom")),
+			wantStatus:   domain.StatusDiscarded,
+			wantAttempts: 3,
+		},
+		{
+			name:         "max attempts falls back to the type's",
+			task:         domain.Task{Type: "once", Attempts: 0},
+			types:        []domain.TypeConfig{{Type: "once", MaxAttempts: 1}},
+			handle:       fail(errors.New("boom")),
+			wantStatus:   domain.StatusFailed,
+			wantDLQ:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "out of attempts before running",
+			task:         domain.Task{MaxAttempts: 3, Attempts: 3, LastError: "worker lost"},
+			handle:       func(context.Context, domain.Task) (json.RawMessage, error) { panic("handler ran") },
+			wantStatus:   domain.StatusFailed,
+			wantDLQ:      true,
+			wantAttempts: 3,
+		},
+		{
+			name:         "timeout is retried",
+			task:         domain.Task{MaxAttempts: 3, Retry: constant, Timeout: 10 * time.Millisecond},
+			handle:       block,
+			wantRetry:    2 * time.Second,
+			wantAttempts: 1,
+			wantErr:      ErrTimeout,
+		},
+		{
+			name:         "deadline is dead-lettered",
+			task:         domain.Task{MaxAttempts: 3, Deadline: time.Now().Add(10 * time.Millisecond)},
+			handle:       block,
+			wantStatus:   domain.StatusFailed,
+			wantDLQ:      true,
+			wantAttempts: 1,
+			wantErr:      ErrDeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fakeQueue{}
+			c := Consumer{
+				Q:          q,
+				Types:      NewTypeRegistry(tt.types, nil, 0),
+				AbortGrace: time.Second,
+			}
+			task := tt.task
+			task.ID = "t1"
+
+			ctx := context.Background()
+			running := &inflight{tasks: map[string]inflightTask{}}
+			start := time.Now()
+			c.process(ctx, ctx, running, ports.Delivery{StreamID: streamID, Task: task}, tt.handle)
+
+			if _, ok := q.dlq[streamID]; ok != tt.wantDLQ {
+				t.Errorf("dead-lettered = %v, want %v", ok, tt.wantDLQ)
+			}
+			// ToDLQ removes the entry itself; every other outcome acks it
+			wantAcks := 1
+			if tt.wantDLQ {
+				wantAcks = 0
+			}
+			if len(q.acked) != wantAcks {
+				t.Errorf("acked %d times, want %d", len(q.acked), wantAcks)
+			}
+
+			if tt.wantRetry > 0 {
+				if len(q.delayed) != 1 {
+					t.Fatalf("scheduled %d retries, want 1", len(q.delayed))
+				}
+				got := q.delayed[0]
+				if delay := got.NextRunAt.Sub(start); delay < tt.wantRetry || delay > tt.wantRetry+time.Second {
+					t.Errorf("retry delay = %v, want %v", delay, tt.wantRetry)
+				}
+				if got.Attempts != tt.wantAttempts {
+					t.Errorf("attempts = %d, want %d", got.Attempts, tt.wantAttempts)
+				}
+				if tt.wantErr != nil && !strings.Contains(got.LastError, tt.wantErr.Error()) {
+					t.Errorf("last error = %q, want it to mention %q", got.LastError, tt.wantErr)
+				}
+				return
+			}
+			if len(q.delayed) != 0 {
+				t.Errorf("scheduled %d retries, want none", len(q.delayed))
+			}
+
+			if q.state == nil {
+				t.Fatal("task state never saved")
+			}
+			if q.state.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", q.state.Status, tt.wantStatus)
+			}
+			if q.state.Attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", q.state.Attempts, tt.wantAttempts)
+			}
+			if tt.wantErr != nil && !strings.Contains(q.state.LastError, tt.wantErr.Error()) {
+				t.Errorf("last error = %q, want it to mention %q", q.state.LastError, tt.wantErr)
+			}
+		})
+	}
+}
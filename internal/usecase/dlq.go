@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"strings"
+	"time"
+)
+
+var ErrDLQEntryNotFound = errors.New("dlq entry not found")
+
+// DLQFilter selects DLQ entries for a bulk requeue. Empty fields match all.
+type DLQFilter struct {
+	Type   string
+	Reason string // substring match
+}
+
+func (f DLQFilter) match(e ports.DLQEntry) bool {
+	if e.Task == nil {
+		return false
+	}
+	if f.Type != "" && e.Task.Type != f.Type {
+		return false
+	}
+	return f.Reason == "" || strings.Contains(e.Reason, f.Reason)
+}
+
+// DeadLetters manages the DLQ. Requeued tasks go through the normal Enqueuer
+// path with their attempts reset.
+type DeadLetters struct {
+	DLQ ports.DLQ
+	Enq Enqueuer
+}
+
+// Requeue enqueues the task behind one DLQ entry again and removes the entry.
+func (d DeadLetters) Requeue(ctx context.Context, streamID string) (string, error) {
+	e, err := d.DLQ.DLQGet(ctx, streamID)
+	if err != nil {
+		return "", err
+	}
+	if e == nil || e.Task == nil {
+		return "", ErrDLQEntryNotFound
+	}
+	return d.requeue(ctx, *e)
+}
+
+// RequeueWhere requeues every DLQ entry matching f and returns how many.
+func (d DeadLetters) RequeueWhere(ctx context.Context, f DLQFilter) (int, error) {
+	n, cursor := 0, ""
+	for {
+		page, next, err := d.DLQ.DLQList(ctx, cursor, 100)
+		if err != nil {
+			return n, err
+		}
+		for _, e := range page {
+			if !f.match(e) {
+				continue
+			}
+			if _, err := d.requeue(ctx, e); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if next == "" {
+			return n, nil
+		}
+		cursor = next
+	}
+}
+
+func (d DeadLetters) requeue(ctx context.Context, e ports.DLQEntry) (string, error) {
+	t := *e.Task
+	t.History = append(t.History, domain.HistoryEntry{
+		At:       e.At,
+		Status:   domain.StatusFailed,
+		Attempts: t.Attempts,
+		Error:    e.Reason,
+	})
+	t.Attempts = 0
+	t.LastError = ""
+	t.NextRunAt = time.Time{}
+
+	id, err := d.Enq.Now(ctx, t)
+	if err != nil {
+		return "", err
+	}
+	return id, d.DLQ.DLQDelete(ctx, e.StreamID)
+}
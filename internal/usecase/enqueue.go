@@ -5,22 +5,98 @@ import (
 	"redisq/internal/domain"
 	"redisq/internal/ports"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// DefaultIdempotencyWindow applies when Enqueuer.IdempotencyWindow is zero.
+const DefaultIdempotencyWindow = 24 * time.Hour
+
 type Enqueuer struct {
-	Q ports.Queue
+	Q     ports.Queue
+	Types *TypeRegistry
+	// Metrics, when set, counts enqueued tasks.
+	Metrics ports.Metrics
+	// IdempotencyWindow is how long Once remembers a key.
+	IdempotencyWindow time.Duration
+	// Results, when set, lets Wait and Call block on a task's result.
+	Results ports.ResultStore
 }
 
 func (e Enqueuer) Now(ctx context.Context, t domain.Task) (string, error) {
-	if t.MaxAttempts == 0 {
-		t.MaxAttempts = 5
-	}
-	return e.Q.Enqueue(ctx, t)
+	id, _, err := e.enqueue(ctx, t, time.Time{}, "")
+	return id, err
 }
 
 func (e Enqueuer) At(ctx context.Context, t domain.Task, runAt time.Time) (string, error) {
-	if t.MaxAttempts == 0 {
-		t.MaxAttempts = 5
+	id, _, err := e.enqueue(ctx, t, runAt, "")
+	return id, err
+}
+
+// Once is Now, or At when runAt is set, deduplicated on an idempotency key:
+// repeating a key within the window returns the original task ID with dup
+// set instead of enqueueing again.
+func (e Enqueuer) Once(ctx context.Context, key string, t domain.Task, runAt time.Time) (id string, dup bool, err error) {
+	return e.enqueue(ctx, t, runAt, key)
+}
+
+// enqueue fills in the type's defaults and stores t. Idempotent and unique
+// tasks go through the guarded path; everything else takes the plain one.
+func (e Enqueuer) enqueue(ctx context.Context, t domain.Task, runAt time.Time, key string) (id string, dup bool, err error) {
+	t.MaxAttempts = maxAttempts(ctx, e.Types, t)
+	t.Queue = queue(ctx, e.Types, t)
+	if t.Unique == nil {
+		t.Unique = e.Types.Lookup(ctx, t.Type).Unique
+	}
+	if t.Unique != nil {
+		if t.UniqueKey, err = uniqueKey(t); err != nil {
+			return "", false, err
+		}
+	}
+
+	ctx, span := startEnqueue(ctx, &t)
+	switch {
+	case key == "" && t.UniqueKey == "" && runAt.IsZero():
+		id, err = e.Q.Enqueue(ctx, t)
+	case key == "" && t.UniqueKey == "":
+		id, err = e.Q.EnqueueDelayed(ctx, t, runAt)
+	default:
+		g := ports.Guard{IdempotencyKey: key, IdempotencyWindow: e.IdempotencyWindow}
+		if g.IdempotencyWindow == 0 {
+			g.IdempotencyWindow = DefaultIdempotencyWindow
+		}
+		if t.Unique != nil {
+			g.UniqueTTL = uniqueTTL(*t.Unique)
+		}
+		id, dup, err = e.Q.EnqueueGuarded(ctx, t, runAt, g)
+		span.SetAttributes(attribute.Bool("redisq.duplicate", dup))
+	}
+	endSpan(span, err)
+
+	if err == nil && !dup && e.Metrics != nil {
+		e.Metrics.Enqueued(t)
+	}
+	return id, dup, err
+}
+
+// maxAttempts resolves the task's own limit, then its type's, then the default.
+func maxAttempts(ctx context.Context, types *TypeRegistry, t domain.Task) int {
+	if t.MaxAttempts > 0 {
+		return t.MaxAttempts
+	}
+	if n := types.Lookup(ctx, t.Type).MaxAttempts; n > 0 {
+		return n
+	}
+	return DefaultMaxAttempts
+}
+
+// queue resolves the task's own queue, then its type's, then the default.
+func queue(ctx context.Context, types *TypeRegistry, t domain.Task) string {
+	if t.Queue != "" {
+		return t.Queue
+	}
+	if q := types.Lookup(ctx, t.Type).Queue; q != "" {
+		return q
 	}
-	return e.Q.EnqueueDelayed(ctx, t, runAt)
+	return domain.DefaultQueue
 }
@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Handlers return (or wrap, with %w) these to change what Consumer.Run does
+// with a failed task.
+var (
+	// SkipRetry sends the task straight to the DLQ.
+	SkipRetry = errors.New("skip retry")
+	// Discard acks the task and drops it without retrying or dead-lettering.
+	Discard = errors.New("discard task")
+)
+
+type retryAfterError struct {
+	delay time.Duration
+	err   error
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("retry after %s: %v", e.delay, e.err)
+}
+
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// RetryAfter fails the attempt with err and retries the task after d instead
+// of the backoff delay, e.g. when a downstream answers 429 with Retry-After.
+// The retry still counts toward MaxAttempts.
+func RetryAfter(d time.Duration, err error) error {
+	if err == nil {
+		err = errors.New("retry requested")
+	}
+	return &retryAfterError{delay: d, err: err}
+}
+
+// retryDelay reports the delay requested through RetryAfter, if any.
+func retryDelay(err error) (time.Duration, bool) {
+	var ra *retryAfterError
+	if errors.As(err, &ra) {
+		return ra.delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"redisq/internal/ports"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Leader runs singleton background loops (scheduler, reaper, periodic tasks)
+// on one process at a time. It campaigns for a Redis lease, renews it every
+// TTL/3 and, while it holds it, runs lead with a context that is cancelled as
+// soon as a renewal fails. If the leader dies its lease expires after TTL and
+// another process takes over.
+type Leader struct {
+	Lease ports.Lease
+	Owner string
+	TTL   time.Duration
+}
+
+// Run campaigns until ctx is done. lead gets the term's fencing token, which
+// loops can check before writing so a stale leader can't act.
+func (l Leader) Run(ctx context.Context, lead func(ctx context.Context, token int64)) error {
+	ticker := time.NewTicker(l.TTL / 3)
+	defer ticker.Stop()
+
+	var (
+		term   int64
+		cancel context.CancelFunc = func() {}
+		wg     sync.WaitGroup
+	)
+	stepDown := func() {
+		if term == 0 {
+			return
+		}
+		cancel()
+		wg.Wait()
+		log.Info().Str("owner", l.Owner).Int64("token", term).Msg("lost leadership")
+		term = 0
+	}
+	defer func() {
+		stepDown()
+		_ = l.Lease.Release(context.WithoutCancel(ctx), l.Owner)
+	}()
+
+	for {
+		token, err := l.Lease.Acquire(ctx, l.Owner, l.TTL)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("leader lease renewal failed")
+			token = 0
+		}
+
+		if token != term {
+			stepDown()
+		}
+		if token != 0 && term == 0 {
+			term = token
+			log.Info().Str("owner", l.Owner).Int64("token", term).Msg("elected leader")
+
+			cancel = startTerm(ctx, &wg, token, lead)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func startTerm(ctx context.Context, wg *sync.WaitGroup, token int64, lead func(ctx context.Context, token int64)) context.CancelFunc {
+	lctx, cancel := context.WithCancel(ctx)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		lead(lctx, token)
+	}()
+	return cancel
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"redisq/internal/domain"
+	"runtime/debug"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware wraps a Handler, mirroring the HTTP middleware in internal/api.
+type Middleware func(Handler) Handler
+
+// Chain wraps h with middlewares. Like the API chain, the last middleware is
+// the outermost one.
+func Chain(h Handler, middlewares ...Middleware) Handler {
+	for _, middleware := range middlewares {
+		h = middleware(h)
+	}
+
+	return h
+}
+
+// Recover turns a handler panic into an ordinary failure, so it follows the
+// retry/DLQ path instead of killing the worker process.
+func Recover(next Handler) Handler {
+	return func(ctx context.Context, t domain.Task) (res json.RawMessage, err error) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				perr, ok := rvr.(error)
+				if !ok {
+					perr = fmt.Errorf("%v", rvr)
+				}
+
+				log.Ctx(ctx).
+					Error().
+					Err(perr).
+					Bytes("stack", debug.Stack()).
+					Msg("panic recover")
+
+				res, err = nil, fmt.Errorf("handler panic: %w", perr)
+			}
+		}()
+
+		return next(ctx, t)
+	}
+}
+
+// TaskContext enriches the context logger with the task ID, type and attempt,
+// and the trace ID when tracing, so every log line written by the handler can
+// be tied to the task.
+func TaskContext(next Handler) Handler {
+	return func(ctx context.Context, t domain.Task) (json.RawMessage, error) {
+		lc := log.With().
+			Str("task_id", t.ID).
+			Str("task_type", t.Type).
+			Int("attempt", t.Attempts+1)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			lc = lc.Str("trace_id", sc.TraceID().String())
+		}
+		ctx = lc.Logger().WithContext(ctx)
+
+		return next(ctx, t)
+	}
+}
+
+// Timer reports how long each attempt took and how it ended.
+func Timer(observe func(t domain.Task, d time.Duration, err error)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, t domain.Task) (json.RawMessage, error) {
+			start := time.Now()
+			res, err := next(ctx, t)
+			observe(t, time.Since(start), err)
+			return res, err
+		}
+	}
+}
+
+// Logger writes one structured log line per attempt with its latency and outcome.
+func Logger(next Handler) Handler {
+	return func(ctx context.Context, t domain.Task) (json.RawMessage, error) {
+		start := time.Now()
+		res, err := next(ctx, t)
+
+		dur := float64(time.Since(start).Nanoseconds()/1e4) / 100.0
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Float64("latency", dur).Msg("task attempt failed")
+			return res, err
+		}
+
+		log.Ctx(ctx).Info().Float64("latency", dur).Msg("task attempt done")
+		return res, nil
+	}
+}
@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"redisq/internal/domain"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNoHandler is returned by the default fallback for task types that have no
+// registered handler, together with SkipRetry so the task is dead-lettered.
+var ErrNoHandler = errors.New("no handler registered for task type")
+
+// Mux routes tasks to handlers by task type. Exact type matches win over
+// prefix matches, and the longest matching prefix wins among prefixes.
+// Tasks nothing matches go to the fallback.
+type Mux struct {
+	mu       sync.RWMutex
+	exact    map[string]Handler
+	prefixes []prefixHandler // sorted longest first
+	fallback Handler
+}
+
+type prefixHandler struct {
+	prefix string
+	h      Handler
+}
+
+func NewMux() *Mux {
+	return &Mux{exact: map[string]Handler{}, fallback: DeadLetterUnknown}
+}
+
+// Handle registers h for tasks whose type is exactly taskType.
+func (m *Mux) Handle(taskType string, h Handler) {
+	if taskType == "" || h == nil {
+		panic("usecase: invalid mux registration")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.exact[taskType]; ok {
+		panic(fmt.Sprintf("usecase: multiple registrations for %q", taskType))
+	}
+	m.exact[taskType] = h
+}
+
+// HandlePrefix registers h for every task type starting with prefix,
+// e.g. "email." for "email.welcome" and "email.reset".
+func (m *Mux) HandlePrefix(prefix string, h Handler) {
+	if prefix == "" || h == nil {
+		panic("usecase: invalid mux registration")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.prefixes {
+		if p.prefix == prefix {
+			panic(fmt.Sprintf("usecase: multiple registrations for prefix %q", prefix))
+		}
+	}
+	m.prefixes = append(m.prefixes, prefixHandler{prefix: prefix, h: h})
+	sort.SliceStable(m.prefixes, func(i, j int) bool {
+		return len(m.prefixes[i].prefix) > len(m.prefixes[j].prefix)
+	})
+}
+
+// Fallback sets the handler used for unknown task types. It defaults to
+// DeadLetterUnknown.
+func (m *Mux) Fallback(h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fallback = h
+}
+
+// Handler returns the handler that would process t.
+func (m *Mux) Handler(t domain.Task) Handler {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if h, ok := m.exact[t.Type]; ok {
+		return h
+	}
+	for _, p := range m.prefixes {
+		if strings.HasPrefix(t.Type, p.prefix) {
+			return p.h
+		}
+	}
+	return m.fallback
+}
+
+// Serve dispatches t to its handler. It has the Handler signature, so a mux
+// can be passed straight to Consumer.Run.
+func (m *Mux) Serve(ctx context.Context, t domain.Task) (json.RawMessage, error) {
+	return m.Handler(t)(ctx, t)
+}
+
+// DeadLetterUnknown fails the task with ErrNoHandler so it goes straight to the DLQ.
+func DeadLetterUnknown(ctx context.Context, t domain.Task) (json.RawMessage, error) {
+	return nil, fmt.Errorf("%w: %q (%w)", ErrNoHandler, t.Type, SkipRetry)
+}
@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"redisq/internal/domain"
+	"testing"
+)
+
+func TestMuxRouting(t *testing.T) {
+	named := func(name string) Handler {
+		return func(context.Context, domain.Task) (json.RawMessage, error) {
+			return json.RawMessage(`"` + name + `"`), nil
+		}
+	}
+
+	m := NewMux()
+	m.Handle("email.welcome", named("welcome"))
+	// registered shortest first, to check the longest still wins
+	m.HandlePrefix("email.", named("email"))
+	m.HandlePrefix("email.marketing.", named("marketing"))
+	m.Handle("email.", named("exact-prefix"))
+
+	tests := []struct {
+		taskType string
+		want     string
+	}{
+		{"email.welcome", "welcome"},
+		{"email.welcome.v2", "email"},
+		{"email.reset", "email"},
+		{"email.marketing.weekly", "marketing"},
+		{"email.marketing", "email"},
+		{"email.", "exact-prefix"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.taskType, func(t *testing.T) {
+			res, err := m.Serve(context.Background(), domain.Task{Type: tt.taskType})
+			if err != nil {
+				t.Fatalf("Serve() error = %v", err)
+			}
+			if got := string(res); got != `"`+tt.want+`"` {
+				t.Errorf("routed to %s, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMuxFallback(t *testing.T) {
+	m := NewMux()
+	m.HandlePrefix("email.", func(context.Context, domain.Task) (json.RawMessage, error) { return nil, nil })
+
+	// the default fallback dead-letters unknown types
+	_, err := m.Serve(context.Background(), domain.Task{Type: "sms.send"})
+	if !errors.Is(err, ErrNoHandler) || !errors.Is(err, SkipRetry) {
+		t.Errorf("default fallback error = %v, want ErrNoHandler and SkipRetry", err)
+	}
+
+	m.Fallback(func(context.Context, domain.Task) (json.RawMessage, error) { return nil, Discard })
+	if _, err := m.Serve(context.Background(), domain.Task{Type: "sms.send"}); !errors.Is(err, Discard) {
+		t.Errorf("custom fallback error = %v, want Discard", err)
+	}
+	if _, err := m.Serve(context.Background(), domain.Task{Type: "email.reset"}); err != nil {
+		t.Errorf("prefix match went to the fallback: %v", err)
+	}
+}
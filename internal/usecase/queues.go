@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"fmt"
+	"redisq/internal/domain"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QueueMode decides how a worker spreads its claims over several queues.
+type QueueMode int
+
+const (
+	// QueueStrict always drains a queue before looking at the ones after
+	// it, so a busy high-priority queue can starve the rest.
+	QueueStrict QueueMode = iota
+	// QueueWeighted starts each claim at a queue picked by weighted
+	// round-robin, so every queue gets its share of the worker. A claim
+	// still falls through to the other queues when that one is empty.
+	QueueWeighted
+)
+
+// QueueWeight is one queue a worker consumes, with its weighted
+// round-robin share.
+type QueueWeight struct {
+	Name   string
+	Weight int
+}
+
+// ParseQueues parses "critical=6,default=3,low=1". Weights are optional and
+// default to 1; for strict priority the order is what counts.
+func ParseQueues(s string) ([]QueueWeight, error) {
+	var out []QueueWeight
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		name, weight, hasWeight := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			return nil, fmt.Errorf("invalid queue list %q", s)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("queue %s listed twice", name)
+		}
+		seen[name] = true
+
+		qw := QueueWeight{Name: name, Weight: 1}
+		if hasWeight {
+			n, err := strconv.Atoi(weight)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid weight for queue %s: %q", name, weight)
+			}
+			qw.Weight = n
+		}
+		out = append(out, qw)
+	}
+	return out, nil
+}
+
+// queuePicker yields the order in which the next claim tries the queues.
+// It is not safe for concurrent use; the consumer's claim loop owns it.
+type queuePicker struct {
+	mode    QueueMode
+	queues  []QueueWeight
+	current []int
+}
+
+func newQueuePicker(mode QueueMode, queues []QueueWeight) *queuePicker {
+	if len(queues) == 0 {
+		queues = []QueueWeight{{Name: domain.DefaultQueue, Weight: 1}}
+	}
+	return &queuePicker{mode: mode, queues: queues, current: make([]int, len(queues))}
+}
+
+func (p *queuePicker) next() []string {
+	order := make([]string, 0, len(p.queues))
+	if p.mode != QueueWeighted {
+		for _, q := range p.queues {
+			order = append(order, q.Name)
+		}
+		return order
+	}
+
+	// smooth weighted round-robin: over sum(weights) claims each queue
+	// leads exactly weight times, spread out rather than in bursts
+	total, lead := 0, 0
+	for i, q := range p.queues {
+		p.current[i] += q.Weight
+		total += q.Weight
+		if p.current[i] > p.current[lead] {
+			lead = i
+		}
+	}
+	p.current[lead] -= total
+
+	order = append(order, p.queues[lead].Name)
+	rest := make([]QueueWeight, 0, len(p.queues)-1)
+	rest = append(rest, p.queues[:lead]...)
+	rest = append(rest, p.queues[lead+1:]...)
+	sort.SliceStable(rest, func(i, j int) bool { return rest[i].Weight > rest[j].Weight })
+	for _, q := range rest {
+		order = append(order, q.Name)
+	}
+	return order
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQueues(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []QueueWeight
+		wantErr bool
+	}{
+		{
+			name: "weighted",
+			in:   "critical=6,default=3,low=1",
+			want: []QueueWeight{{"critical", 6}, {"default", 3}, {"low", 1}},
+		},
+		{
+			name: "weights default to 1",
+			in:   "critical,default",
+			want: []QueueWeight{{"critical", 1}, {"default", 1}},
+		},
+		{
+			name: "spaces around entries",
+			in:   " critical=2 , low ",
+			want: []QueueWeight{{"critical", 2}, {"low", 1}},
+		},
+		{name: "single", in: "default", want: []QueueWeight{{"default", 1}}},
+		{name: "empty", in: "", wantErr: true},
+		{name: "empty entry", in: "critical,,low", wantErr: true},
+		{name: "missing name", in: "=3", wantErr: true},
+		{name: "duplicate", in: "low,critical,low", wantErr: true},
+		{name: "zero weight", in: "low=0", wantErr: true},
+		{name: "negative weight", in: "low=-1", wantErr: true},
+		{name: "non-numeric weight", in: "low=high", wantErr: true},
+		{name: "empty weight", in: "low=", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQueues(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQueues(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQueues(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueuePicker(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   QueueMode
+		queues []QueueWeight
+		want   [][]string // orders of consecutive claims
+	}{
+		{
+			name: "no queues means default",
+			mode: QueueWeighted,
+			want: [][]string{{"default"}, {"default"}},
+		},
+		{
+			name:   "strict keeps the listed order",
+			mode:   QueueStrict,
+			queues: []QueueWeight{{"low", 1}, {"critical", 6}},
+			want:   [][]string{{"low", "critical"}, {"low", "critical"}},
+		},
+		{
+			name:   "weighted spreads leads, rest by weight",
+			mode:   QueueWeighted,
+			queues: []QueueWeight{{"a", 5}, {"b", 1}, {"c", 1}},
+			want: [][]string{
+				{"a", "b", "c"},
+				{"a", "b", "c"},
+				{"b", "a", "c"},
+				{"a", "b", "c"},
+				{"c", "a", "b"},
+				{"a", "b", "c"},
+				{"a", "b", "c"},
+				// the cycle repeats
+				{"a", "b", "c"},
+			},
+		},
+		{
+			name:   "weighted with equal weights alternates",
+			mode:   QueueWeighted,
+			queues: []QueueWeight{{"x", 1}, {"y", 1}},
+			want:   [][]string{{"x", "y"}, {"y", "x"}, {"x", "y"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newQueuePicker(tt.mode, tt.queues)
+			for i, want := range tt.want {
+				if got := p.next(); !reflect.DeepEqual(got, want) {
+					t.Fatalf("claim %d: next() = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestQueuePickerShares(t *testing.T) {
+	queues := []QueueWeight{{"critical", 6}, {"default", 3}, {"low", 1}}
+	p := newQueuePicker(QueueWeighted, queues)
+
+	leads := map[string]int{}
+	for range 10 * 10 {
+		leads[p.next()[0]]++
+	}
+	for _, q := range queues {
+		if leads[q.Name] != q.Weight*10 {
+			t.Errorf("%s led %d of 100 claims, want %d", q.Name, leads[q.Name], q.Weight*10)
+		}
+	}
+}
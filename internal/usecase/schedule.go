@@ -1 +1,226 @@
 package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultMisfire applies when PeriodicScheduler.Misfire is zero. It covers a
+// failover under the default --leader-ttl.
+const DefaultMisfire = 30 * time.Second
+
+// maxCatchUp caps how many missed occurrences CatchUpAll enqueues at once.
+const maxCatchUp = 100
+
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Every occurrence of a periodic task is materialised as a delayed task
+// through Enqueuer.At, so it flows through the normal ZSET/stream pipeline.
+// Each occurrence is claimed with a compare-and-set on the definition's last
+// fired time, so with many workers only one of them enqueues it.
+type PeriodicScheduler struct {
+	Store    ports.PeriodicStore
+	Enq      Enqueuer
+	Interval time.Duration
+	// Misfire is how late an occurrence may be noticed and still run under
+	// CatchUpSkip. It should cover a leader failover, or occurrences due
+	// meanwhile are dropped. Zero means DefaultMisfire, or twice the
+	// Interval if that is longer.
+	Misfire time.Duration
+	// Lease and Token, when set, fence the loop: a tick does nothing once a
+	// newer leader term has started.
+	Lease ports.Lease
+	Token int64
+}
+
+// LoadPeriodicFile reads a JSON array of domain.Periodic.
+func LoadPeriodicFile(path string) ([]domain.Periodic, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ps []domain.Periodic
+	if err := json.Unmarshal(b, &ps); err != nil {
+		return nil, fmt.Errorf("invalid periodic file %s: %w", path, err)
+	}
+	for i := range ps {
+		if err := ValidatePeriodic(&ps[i]); err != nil {
+			return nil, err
+		}
+	}
+	return ps, nil
+}
+
+// ValidatePeriodic checks p and fills in defaults.
+func ValidatePeriodic(p *domain.Periodic) error {
+	if p.Name == "" || p.Type == "" {
+		return errors.New("periodic task needs a name and a type")
+	}
+	if (p.Cron == "") == (p.EveryMs <= 0) {
+		return fmt.Errorf("periodic task %s needs exactly one of cron or every_ms", p.Name)
+	}
+	if p.Cron != "" {
+		if _, err := cronParser.Parse(p.Cron); err != nil {
+			return fmt.Errorf("periodic task %s: %w", p.Name, err)
+		}
+	}
+	switch p.CatchUp {
+	case "":
+		p.CatchUp = domain.CatchUpSkip
+	case domain.CatchUpSkip, domain.CatchUpLatest, domain.CatchUpAll:
+	default:
+		return fmt.Errorf("periodic task %s: invalid catch_up %q", p.Name, p.CatchUp)
+	}
+	return nil
+}
+
+func (s PeriodicScheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if s.Lease != nil {
+				if ok, err := s.Lease.Valid(ctx, s.Token); err != nil || !ok {
+					log.Ctx(ctx).Warn().Err(err).Msg("periodic scheduler fenced off, skipping tick")
+					continue
+				}
+			}
+			ps, err := s.Store.Periodics(ctx)
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("failed to load periodic tasks")
+				continue
+			}
+			now := time.Now()
+			for _, p := range ps {
+				if err := s.fire(ctx, p, now); err != nil {
+					log.Ctx(ctx).Error().Err(err).Str("periodic", p.Name).Msg("periodic task failed to fire")
+				}
+			}
+		}
+	}
+}
+
+func (s PeriodicScheduler) fire(ctx context.Context, p domain.Periodic, now time.Time) error {
+	if err := ValidatePeriodic(&p); err != nil {
+		return err
+	}
+
+	last, err := s.Store.LastFired(ctx, p.Name)
+	if err != nil {
+		return err
+	}
+	if last.IsZero() {
+		// newly registered: start from now, don't backfill
+		_, err := s.Store.AdvanceFired(ctx, p.Name, last, now)
+		return err
+	}
+
+	occs := occurrences(p, last, now)
+	if len(occs) == 0 {
+		return nil
+	}
+
+	run := occs
+	switch p.CatchUp {
+	case domain.CatchUpLatest:
+		run = occs[len(occs)-1:]
+	case domain.CatchUpAll:
+		if len(run) > maxCatchUp {
+			run = run[len(run)-maxCatchUp:]
+		}
+	default:
+		misfire := s.Misfire
+		if misfire == 0 {
+			misfire = max(2*s.Interval, DefaultMisfire)
+		}
+		for len(run) > 0 && now.Sub(run[0]) > misfire {
+			run = run[1:]
+		}
+	}
+
+	if skipped := len(occs) - len(run); skipped > 0 {
+		log.Ctx(ctx).Warn().Str("periodic", p.Name).Int("skipped", skipped).Msg("skipped missed occurrences")
+	}
+
+	// Enqueue first, then advance past the occurrence: if either step fails
+	// the occurrence is retried on the next tick rather than lost, and its
+	// deterministic ID keeps the retry from storing it twice.
+	prev := last
+	for _, at := range run {
+		if err := s.enqueueOccurrence(ctx, p, at); err != nil {
+			return err
+		}
+		won, err := s.Store.AdvanceFired(ctx, p.Name, prev, at)
+		if err != nil || !won {
+			return err
+		}
+		prev = at
+	}
+	if end := occs[len(occs)-1]; !prev.Equal(end) {
+		_, err = s.Store.AdvanceFired(ctx, p.Name, prev, end)
+	}
+	return err
+}
+
+// enqueueOccurrence enqueues p's occurrence at at, unless an earlier attempt
+// already did.
+func (s PeriodicScheduler) enqueueOccurrence(ctx context.Context, p domain.Periodic, at time.Time) error {
+	t := domain.Task{
+		// deterministic, so an occurrence is never stored twice
+		ID:          fmt.Sprintf("periodic:%s:%d", p.Name, at.UnixMilli()),
+		Type:        p.Type,
+		Queue:       p.Queue,
+		Payload:     p.Payload,
+		MaxAttempts: p.MaxAttempts,
+		Timeout:     time.Duration(p.TimeoutMs) * time.Millisecond,
+	}
+	cur, err := s.Enq.Q.Get(ctx, t.ID)
+	if err != nil || cur != nil {
+		return err
+	}
+	_, err = s.Enq.At(ctx, t, at)
+	return err
+}
+
+// occurrences returns p's occurrences in (after, until], keeping only the
+// most recent thousand so a long outage can't stall the loop.
+func occurrences(p domain.Periodic, after, until time.Time) []time.Time {
+	var next func(time.Time) time.Time
+	if p.Cron != "" {
+		sched, err := cronParser.Parse(p.Cron)
+		if err != nil {
+			return nil
+		}
+		next = sched.Next
+	} else {
+		every := time.Duration(p.EveryMs) * time.Millisecond
+		// anchored on a fixed grid, so every instance agrees on it
+		next = func(t time.Time) time.Time { return t.Truncate(every).Add(every) }
+		if until.Sub(after)/every > 10*maxCatchUp {
+			after = until.Add(-10 * maxCatchUp * every)
+		}
+	}
+
+	var out []time.Time
+	for t := next(after); !t.IsZero() && !t.After(until); t = next(t) {
+		out = append(out, t)
+		if len(out) > 10*maxCatchUp {
+			out = out[1:]
+		}
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"redisq/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOccurrences(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return base.Add(d) }
+
+	tests := []struct {
+		name         string
+		p            domain.Periodic
+		after, until time.Time
+		want         []time.Time
+		wantLen      int // checked instead of want when set
+	}{
+		{
+			name:  "every, on the grid",
+			p:     domain.Periodic{EveryMs: 60_000},
+			after: at(0), until: at(3 * time.Minute),
+			want: []time.Time{at(time.Minute), at(2 * time.Minute), at(3 * time.Minute)},
+		},
+		{
+			name:  "every, off the grid",
+			p:     domain.Periodic{EveryMs: 60_000},
+			after: at(30 * time.Second), until: at(150 * time.Second),
+			want: []time.Time{at(time.Minute), at(2 * time.Minute)},
+		},
+		{
+			name:  "every, none due",
+			p:     domain.Periodic{EveryMs: 60_000},
+			after: at(time.Minute), until: at(90 * time.Second),
+		},
+		{
+			name:  "cron",
+			p:     domain.Periodic{Cron: "*/15 * * * *"},
+			after: at(0), until: at(time.Hour),
+			want: []time.Time{at(15 * time.Minute), at(30 * time.Minute), at(45 * time.Minute), at(time.Hour)},
+		},
+		{
+			name:  "cron descriptor",
+			p:     domain.Periodic{Cron: "@daily"},
+			after: at(time.Hour), until: at(49 * time.Hour),
+			want: []time.Time{at(24 * time.Hour), at(48 * time.Hour)},
+		},
+		{
+			name:  "invalid cron",
+			p:     domain.Periodic{Cron: "not a cron"},
+			after: at(0), until: at(time.Hour),
+		},
+		{
+			name:  "every, long outage keeps the most recent",
+			p:     domain.Periodic{EveryMs: 1000},
+			after: at(0), until: at(24 * time.Hour),
+			wantLen: 10 * maxCatchUp,
+		},
+		{
+			name:  "cron, long outage keeps the most recent",
+			p:     domain.Periodic{Cron: "* * * * *"},
+			after: at(0), until: at(48 * time.Hour),
+			wantLen: 10 * maxCatchUp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := occurrences(tt.p, tt.after, tt.until)
+			if tt.wantLen > 0 {
+				if len(got) != tt.wantLen {
+					t.Fatalf("occurrences() returned %d, want %d", len(got), tt.wantLen)
+				}
+				if last := got[len(got)-1]; !last.Equal(tt.until) {
+					t.Errorf("last occurrence = %v, want %v", last, tt.until)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("occurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
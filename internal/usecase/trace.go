@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"context"
+	"redisq/internal/domain"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("redisq/internal/usecase")
+
+// startEnqueue starts the producer span for t and stores its W3C trace
+// context on the task. A task that already has one (e.g. requeued from the
+// DLQ) keeps it unless ctx is part of a trace.
+func startEnqueue(ctx context.Context, t *domain.Task) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(ctx, "enqueue "+t.Type,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(taskAttributes(*t)...),
+	)
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		t.Trace = carrier
+	}
+	return ctx, span
+}
+
+// startAttempt starts the span for one attempt of t, as a child of the span
+// it was enqueued under.
+func startAttempt(ctx context.Context, t domain.Task) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(t.Trace))
+	return tracer.Start(ctx, "process "+t.Type,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(taskAttributes(t)...),
+		trace.WithAttributes(attribute.Int("redisq.task.attempt", t.Attempts+1)),
+	)
+}
+
+// endSpan records err on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func taskAttributes(t domain.Task) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", "redis"),
+		attribute.String("messaging.destination.name", t.Queue),
+		attribute.String("redisq.task.type", t.Type),
+	}
+	if t.ID != "" {
+		attrs = append(attrs, attribute.String("messaging.message.id", t.ID))
+	}
+	return attrs
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"redisq/internal/domain"
+	"redisq/internal/ports"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultMaxAttempts applies when neither the task nor its type sets one.
+const DefaultMaxAttempts = 5
+
+var _ ports.TypeResolver = (*TypeRegistry)(nil)
+
+// TypeRegistry holds the per-type contracts. Configs come from a file given
+// at startup and from the Redis store; Redis entries win, so types can be
+// tuned without a redeploy. Store lookups are cached for TTL.
+//
+// A nil *TypeRegistry is valid and knows no types.
+type TypeRegistry struct {
+	Store ports.TypeStore
+	TTL   time.Duration
+
+	static map[string]domain.TypeConfig
+
+	mu       sync.RWMutex
+	cache    map[string]domain.TypeConfig
+	loadedAt time.Time
+}
+
+func NewTypeRegistry(static []domain.TypeConfig, store ports.TypeStore, ttl time.Duration) *TypeRegistry {
+	r := &TypeRegistry{Store: store, TTL: ttl, static: map[string]domain.TypeConfig{}}
+	for _, tc := range static {
+		r.static[tc.Type] = tc
+	}
+	return r
+}
+
+// LoadTypeFile reads a JSON array of domain.TypeConfig.
+func LoadTypeFile(path string) ([]domain.TypeConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tcs []domain.TypeConfig
+	if err := json.Unmarshal(b, &tcs); err != nil {
+		return nil, fmt.Errorf("invalid type config file %s: %w", path, err)
+	}
+	for _, tc := range tcs {
+		if err := ValidateTypeConfig(tc); err != nil {
+			return nil, err
+		}
+	}
+	return tcs, nil
+}
+
+func ValidateTypeConfig(tc domain.TypeConfig) error {
+	if tc.Type == "" {
+		return fmt.Errorf("type config without a type")
+	}
+	if tc.MaxAttempts < 0 || tc.TimeoutMs < 0 || (tc.Unique != nil && tc.Unique.TTLMs < 0) {
+		return fmt.Errorf("type config %s: negative limits", tc.Type)
+	}
+	if tc.Retry != nil {
+		if _, err := tc.Retry.RetryPolicy(); err != nil {
+			return fmt.Errorf("type config %s: %w", tc.Type, err)
+		}
+	}
+	switch tc.OnExhausted {
+	case "", domain.OnExhaustedDLQ, domain.OnExhaustedDiscard:
+	default:
+		return fmt.Errorf("type config %s: invalid on_exhausted %q", tc.Type, tc.OnExhausted)
+	}
+	return nil
+}
+
+// Lookup returns the config for taskType, or a zero config if it has none.
+func (r *TypeRegistry) Lookup(ctx context.Context, taskType string) domain.TypeConfig {
+	if r == nil {
+		return domain.TypeConfig{Type: taskType}
+	}
+	if tc, ok := r.all(ctx)[taskType]; ok {
+		return tc
+	}
+	return domain.TypeConfig{Type: taskType}
+}
+
+// List returns every known type config sorted by type.
+func (r *TypeRegistry) List(ctx context.Context) []domain.TypeConfig {
+	if r == nil {
+		return nil
+	}
+	all := r.all(ctx)
+	out := make([]domain.TypeConfig, 0, len(all))
+	for _, tc := range all {
+		out = append(out, tc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out
+}
+
+func (r *TypeRegistry) all(ctx context.Context) map[string]domain.TypeConfig {
+	r.mu.RLock()
+	cache, fresh := r.cache, r.cache != nil && time.Since(r.loadedAt) < r.TTL
+	r.mu.RUnlock()
+	if fresh {
+		return cache
+	}
+
+	merged := make(map[string]domain.TypeConfig, len(r.static))
+	for k, v := range r.static {
+		merged[k] = v
+	}
+	if r.Store != nil {
+		stored, err := r.Store.TypeConfigs(ctx)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("failed to load type configs, using last known")
+			if cache != nil {
+				return cache
+			}
+		}
+		for _, tc := range stored {
+			merged[tc.Type] = tc
+		}
+	}
+
+	r.mu.Lock()
+	r.cache, r.loadedAt = merged, time.Now()
+	r.mu.Unlock()
+	return merged
+}
@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"redisq/internal/domain"
+	"slices"
+	"time"
+)
+
+// DefaultUniqueTTL caps a uniqueness lock when the spec sets no TTL. A task
+// still pending after that no longer blocks duplicates.
+const DefaultUniqueTTL = 24 * time.Hour
+
+// ErrUniquePayload is returned for a unique task with Fields whose payload
+// is not a JSON object.
+var ErrUniquePayload = errors.New("unique task fields need a JSON object payload")
+
+// uniqueKey derives t's uniqueness key from its type and the values of the
+// spec's payload fields. Field order doesn't matter and values are compared
+// as compacted JSON; a missing field counts as null.
+func uniqueKey(t domain.Task) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(t.Type))
+
+	if len(t.Unique.Fields) > 0 {
+		var payload map[string]json.RawMessage
+		if len(t.Payload) > 0 {
+			if err := json.Unmarshal(t.Payload, &payload); err != nil {
+				return "", fmt.Errorf("%w: %s", ErrUniquePayload, t.Type)
+			}
+		}
+		for _, f := range slices.Sorted(slices.Values(t.Unique.Fields)) {
+			v := payload[f]
+			if len(v) == 0 {
+				v = json.RawMessage("null")
+			}
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, v); err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "\x00%s=%s", f, buf.Bytes())
+		}
+	}
+
+	return t.Type + ":" + hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+func uniqueTTL(spec domain.UniqueSpec) time.Duration {
+	if spec.TTLMs > 0 {
+		return time.Duration(spec.TTLMs) * time.Millisecond
+	}
+	return DefaultUniqueTTL
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"redisq/internal/domain"
+	"strings"
+	"testing"
+)
+
+func TestUniqueKey(t *testing.T) {
+	task := func(typ, payload string, fields ...string) domain.Task {
+		t := domain.Task{Type: typ, Unique: &domain.UniqueSpec{Fields: fields}}
+		if payload != "" {
+			t.Payload = json.RawMessage(payload)
+		}
+		return t
+	}
+
+	tests := []struct {
+		name string
+		a, b domain.Task
+		same bool
+	}{
+		{
+			name: "same field values",
+			a:    task("email.send", `{"user_id":1,"body":"a"}`, "user_id"),
+			b:    task("email.send", `{"user_id":1,"body":"b"}`, "user_id"),
+			same: true,
+		},
+		{
+			name: "different field values",
+			a:    task("email.send", `{"user_id":1}`, "user_id"),
+			b:    task("email.send", `{"user_id":2}`, "user_id"),
+		},
+		{
+			name: "different types",
+			a:    task("email.send", `{"user_id":1}`, "user_id"),
+			b:    task("sms.send", `{"user_id":1}`, "user_id"),
+		},
+		{
+			name: "field order doesn't matter",
+			a:    task("x", `{"a":1,"b":2}`, "a", "b"),
+			b:    task("x", `{"b":2,"a":1}`, "b", "a"),
+			same: true,
+		},
+		{
+			name: "values compared as compacted JSON",
+			a:    task("x", `{"a":{"k": [1, 2]}}`, "a"),
+			b:    task("x", `{"a":{"k":[1,2]}}`, "a"),
+			same: true,
+		},
+		{
+			name: "missing field counts as null",
+			a:    task("x", `{}`, "a"),
+			b:    task("x", `{"a":null}`, "a"),
+			same: true,
+		},
+		{
+			name: "no payload counts as nulls",
+			a:    task("x", "", "a"),
+			b:    task("x", `{"a":null}`, "a"),
+			same: true,
+		},
+		{
+			name: "string and number differ",
+			a:    task("x", `{"a":"1"}`, "a"),
+			b:    task("x", `{"a":1}`, "a"),
+		},
+		{
+			name: "no fields keys on the type alone",
+			a:    task("x", `{"a":1}`),
+			b:    task("x", `[1,2]`),
+			same: true,
+		},
+		{
+			name: "field names are part of the key",
+			a:    task("x", `{"a":1,"b":null}`, "a"),
+			b:    task("x", `{"a":null,"b":1}`, "b"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ka, err := uniqueKey(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kb, err := uniqueKey(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (ka == kb) != tt.same {
+				t.Errorf("uniqueKey() = %q and %q, want same %v", ka, kb, tt.same)
+			}
+			if !strings.HasPrefix(ka, tt.a.Type+":") {
+				t.Errorf("uniqueKey() = %q, want the type as prefix", ka)
+			}
+		})
+	}
+}
+
+func TestUniqueKeyInvalidPayload(t *testing.T) {
+	for _, payload := range []string{`[1,2]`, `"a"`, `{`} {
+		tk := domain.Task{Type: "x", Payload: json.RawMessage(payload), Unique: &domain.UniqueSpec{Fields: []string{"a"}}}
+		if _, err := uniqueKey(tk); !errors.Is(err, ErrUniquePayload) {
+			t.Errorf("uniqueKey(%s) error = %v, want ErrUniquePayload", payload, err)
+		}
+	}
+}
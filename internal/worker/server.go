@@ -4,28 +4,64 @@ package worker
 import (
 	"context"
 	"errors"
+	"net/http"
 	"os"
 	"os/signal"
 	"redisq/internal/config"
 	"redisq/internal/domain"
+	"redisq/internal/infra/metrics"
 	"redisq/internal/infra/redisq"
+	"redisq/internal/ports"
 	"redisq/internal/usecase"
+	"redisq/pkg/backoff"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
 type WorkerConfig struct {
-	ConsumerName string
-	BaseBackoff  time.Duration
-	MaxBackoff   time.Duration
+	ConsumerName      string
+	BaseBackoff       time.Duration
+	MaxBackoff        time.Duration
+	TypeRetry         map[string]backoff.RetryPolicy
+	VisibilityTimeout time.Duration
+	ReapInterval      time.Duration
+	Concurrency       int
+	TaskTimeout       time.Duration
+	TypeTimeouts      map[string]time.Duration
+	ShutdownGrace     time.Duration
+	HandBack          usecase.HandBack
+	// TypesFile optionally seeds the task type registry (JSON array).
+	TypesFile string
+	// LeaderTTL is the leader lease lifetime; a dead leader is replaced
+	// within about this long.
+	LeaderTTL time.Duration
+	// PeriodicFile optionally registers periodic tasks (JSON array).
+	PeriodicFile string
+	// Queues are consumed in QueueMode; empty means the default queue.
+	Queues    []usecase.QueueWeight
+	QueueMode usecase.QueueMode
+	// MetricsAddr, when set, serves Prometheus metrics on /metrics there.
+	MetricsAddr string
+	// ResultTTL is how long task results are kept; zero stores none, but
+	// callers waiting on a task are still woken with its outcome.
+	ResultTTL time.Duration
 }
 
-func Run(cfg WorkerConfig) error {
+// Run starts the scheduler, reaper and consumer loop, dispatching every
+// claimed task through mux.
+func Run(cfg WorkerConfig, mux *usecase.Mux) error {
 	appCfg := config.Load()
 	log.Info().Msgf("Worker using stream: %s, group: %s", appCfg.Redis.StreamKey, appCfg.Redis.Group)
+	for _, q := range cfg.Queues {
+		// make sure Init creates a group on every queue this worker reads
+		appCfg.Redis.Queues = append(appCfg.Redis.Queues, q.Name)
+	}
+	appCfg.Redis.ResultTTL = cfg.ResultTTL
 	cli := redisq.New(appCfg.Redis)
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -38,29 +74,146 @@ func Run(cfg WorkerConfig) error {
 		log.Info().Msg("Consumer group already exists, continuing...")
 	}
 
-	// Run scheduler
-	sched := redisq.NewScheduler(cli, 1*time.Second)
-	go func() {
-		if err := sched.Run(ctx); err != nil {
-			log.Ctx(ctx).Error().Err(err).Msg("scheduler stopped with error")
+	var static []domain.TypeConfig
+	if cfg.TypesFile != "" {
+		var err error
+		if static, err = usecase.LoadTypeFile(cfg.TypesFile); err != nil {
+			return err
+		}
+	}
+	types := usecase.NewTypeRegistry(static, cli, 30*time.Second)
+
+	var m ports.Metrics
+	if cfg.MetricsAddr != "" {
+		pm := metrics.New(cli)
+		go serveMetrics(ctx, cfg.MetricsAddr, pm.Handler())
+		m = pm
+	}
+
+	if cfg.PeriodicFile != "" {
+		ps, err := usecase.LoadPeriodicFile(cfg.PeriodicFile)
+		if err != nil {
+			return err
 		}
+		for _, p := range ps {
+			if err := cli.SavePeriodic(ctx, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Scheduler, periodic scheduler and reaper run on the elected leader only
+	leader := usecase.Leader{
+		Lease: cli,
+		Owner: cfg.ConsumerName + ":" + uuid.NewString(),
+		TTL:   cfg.LeaderTTL,
+	}
+	leaderCtx, cancelLeader := context.WithCancel(ctx)
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		_ = leader.Run(leaderCtx, func(ctx context.Context, token int64) {
+			runLeaderLoops(ctx, cli, cfg, types, m, token)
+		})
+	}()
+	// step down even if the consumer fails, and release the lease before
+	// returning so another worker takes over without waiting for the TTL
+	defer func() {
+		cancelLeader()
+		<-leaderDone
 	}()
 
 	consumer := usecase.Consumer{
 		Q:            cli,
 		ConsumerName: cfg.ConsumerName,
+		Types:        types,
+		Cancels:      cli,
 		BaseBackoff:  cfg.BaseBackoff,
 		MaxBackoff:   cfg.MaxBackoff,
+		TypeRetry:    cfg.TypeRetry,
+		Concurrency:  cfg.Concurrency,
+
+		DefaultTimeout: cfg.TaskTimeout,
+		TypeTimeouts:   cfg.TypeTimeouts,
+
+		ShutdownGrace: cfg.ShutdownGrace,
+		HandBack:      cfg.HandBack,
+
+		Queues:    cfg.Queues,
+		QueueMode: cfg.QueueMode,
+		Metrics:   m,
+
+		Results:   cli,
+		ResultTTL: cfg.ResultTTL,
+		// keep long-running handlers' entries away from the reaper
+		Heartbeat: cfg.VisibilityTimeout / 3,
 	}
 
-	handler := func(ctx context.Context, t domain.Task) error {
-		if t.Type == "demo.fail" && t.Attempts < 2 {
-			return errors.New("simulated failure")
-		}
-		log.Ctx(ctx).Info().Msgf("processed task %s type=%s attempts=%d", t.ID, t.Type, t.Attempts)
-		return nil
+	log.Info().Msgf("Worker %s started with concurrency %d. Waiting for tasks...", cfg.ConsumerName, cfg.Concurrency)
+	middlewares := []usecase.Middleware{usecase.Recover}
+	if m != nil {
+		middlewares = append(middlewares, usecase.Timer(m.Attempted))
 	}
+	middlewares = append(middlewares, usecase.Logger, usecase.TaskContext)
+	handler := usecase.Chain(mux.Serve, middlewares...)
 
-	log.Info().Msgf("Worker %s started. Waiting for tasks...", cfg.ConsumerName)
 	return consumer.Run(ctx, handler)
 }
+
+// runLeaderLoops runs the singleton loops for one leadership term and returns
+// once they have all stopped.
+func runLeaderLoops(ctx context.Context, cli *redisq.Client, cfg WorkerConfig, types *usecase.TypeRegistry, m ports.Metrics, token int64) {
+	sched := redisq.NewScheduler(cli, 1*time.Second)
+	sched.Token = token
+	sched.Types = types
+
+	periodic := usecase.PeriodicScheduler{
+		Store:    cli,
+		Enq:      usecase.Enqueuer{Q: cli, Types: types, Metrics: m},
+		Interval: 1 * time.Second,
+		// a failover takes up to a lease lifetime plus a campaign tick
+		Misfire: max(usecase.DefaultMisfire, 2*cfg.LeaderTTL),
+		Lease:   cli,
+		Token:   token,
+	}
+
+	reaper := redisq.NewReaper(cli, cfg.ConsumerName, cfg.ReapInterval, cfg.VisibilityTimeout)
+	reaper.Token = token
+	reaper.Metrics = m
+	reaper.ResultTTL = cfg.ResultTTL
+
+	loops := map[string]func(context.Context) error{
+		"scheduler":          sched.Run,
+		"periodic scheduler": periodic.Run,
+		"reaper":             reaper.Run,
+	}
+
+	var wg sync.WaitGroup
+	for name, run := range loops {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Ctx(ctx).Error().Err(err).Msgf("%s stopped with error", name)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// serveMetrics runs the metrics listener until ctx is done.
+func serveMetrics(ctx context.Context, addr string, h http.Handler) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", h)
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.WithoutCancel(ctx))
+	}()
+
+	log.Info().Msgf("worker metrics serving on %s", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error().Err(err).Msg("metrics listener failed")
+	}
+}
@@ -2,17 +2,117 @@ package backoff
 
 import (
 	"math"
+	"math/rand/v2"
 	"time"
 )
 
+// RetryPolicy returns how long to wait before the given retry attempt
+// (1 for the first retry).
+type RetryPolicy interface {
+	Delay(attempt int) time.Duration
+}
+
+// Constant waits the same Interval before every retry.
+type Constant struct {
+	Interval time.Duration
+}
+
+func (p Constant) Delay(attempt int) time.Duration { return p.Interval }
+
+// Linear waits Base * attempt, capped at Max.
+type Linear struct {
+	Base, Max time.Duration
+}
+
+func (p Linear) Delay(attempt int) time.Duration {
+	return capped(scale(p.Base, float64(norm(attempt))), p.Max)
+}
+
+// Exponential waits Base * 2^(attempt-1), capped at Max, with +/- 20% jitter.
+type Exponential struct {
+	Base, Max time.Duration
+}
+
+func (p Exponential) Delay(attempt int) time.Duration {
+	return ExponentialJitter(p.Base, p.Max, attempt)
+}
+
+// DecorrelatedJitter is the "decorrelated jitter" schedule: each delay is
+// drawn between Base and three times the previous delay, capped at Max. It
+// spreads out retries that failed at the same moment better than plain
+// exponential jitter.
+type DecorrelatedJitter struct {
+	Base, Max time.Duration
+}
+
+func (p DecorrelatedJitter) Delay(attempt int) time.Duration {
+	d := p.Base
+	for range norm(attempt) {
+		d = capped(between(p.Base, scale(d, 3)), p.Max)
+	}
+	return d
+}
+
+// Fibonacci waits Base * fib(attempt) (1, 1, 2, 3, 5, ...), capped at Max.
+type Fibonacci struct {
+	Base, Max time.Duration
+}
+
+func (p Fibonacci) Delay(attempt int) time.Duration {
+	a, b := 1.0, 1.0
+	for range norm(attempt) - 1 {
+		a, b = b, a+b
+	}
+	return capped(scale(p.Base, a), p.Max)
+}
+
+// ExponentialJitter returns base * 2^(attempt-1), capped at max, with +/- 20%
+// jitter.
 func ExponentialJitter(base, max time.Duration, attempt int) time.Duration {
+	mul := math.Pow(2, float64(norm(attempt)-1))
+	d := capped(scale(base, mul), max)
+
+	// jitter: +/- 20%
+	j := scale(d, 0.2)
+	return between(d-j, capped(d+j, 0))
+}
+
+func norm(attempt int) int {
 	if attempt <= 0 {
-		attempt = 1
+		return 1
 	}
-	mul := math.Pow(2, float64(attempt-1))
-	d := min(time.Duration(float64(base)*mul), max)
+	return attempt
+}
+
+// scale returns d * f, saturating at the largest Duration instead of
+// overflowing; late attempts of unbounded policies get there quickly.
+func scale(d time.Duration, f float64) time.Duration {
+	x := float64(d) * f
+	if math.IsNaN(x) || x >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(x)
+}
 
-	// simple jitter: +/- 20%
-	j := time.Duration(float64(d) * 0.2)
-	return d - j + time.Duration(int64(j)*time.Now().UnixNano()%int64(2*j))
+// capped limits d to max; a zero max means no limit. Overflowed (negative)
+// durations are treated as exceeding max, or as the largest Duration when
+// there is none.
+func capped(d, max time.Duration) time.Duration {
+	switch {
+	case max > 0 && (d > max || d < 0):
+		return max
+	case d < 0:
+		return math.MaxInt64
+	}
+	return d
+}
+
+// between returns a random duration in [lo, hi). math/rand/v2's top-level
+// functions use a randomly seeded, goroutine-safe source. The span is taken
+// as unsigned, so it can't overflow even when hi-lo exceeds MaxInt64.
+func between(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(rand.Uint64N(uint64(hi)-uint64(lo)))
 }
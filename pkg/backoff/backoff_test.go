@@ -0,0 +1,121 @@
+package backoff
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDelayBounds(t *testing.T) {
+	const base = time.Second
+	attempts := []int{-1, 0, 1, 2, 10, 30, 35, 62, 63, 64, 100, 1000, 5000}
+
+	tests := []struct {
+		name string
+		p    RetryPolicy
+		max  time.Duration // 0 = unbounded
+	}{
+		{"constant", Constant{Interval: base}, base},
+		{"linear", Linear{Base: base}, 0},
+		{"linear capped", Linear{Base: base, Max: time.Minute}, time.Minute},
+		{"exponential", Exponential{Base: base}, 0},
+		{"exponential capped", Exponential{Base: base, Max: time.Minute}, time.Minute * 12 / 10},
+		{"decorrelated", DecorrelatedJitter{Base: base}, 0},
+		{"decorrelated capped", DecorrelatedJitter{Base: base, Max: time.Minute}, time.Minute},
+		{"fibonacci", Fibonacci{Base: base}, 0},
+		{"fibonacci capped", Fibonacci{Base: base, Max: time.Minute}, time.Minute},
+		{"exponential huge base", Exponential{Base: math.MaxInt64 / 2}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, a := range attempts {
+				d := tt.p.Delay(a)
+				if d < 0 {
+					t.Errorf("Delay(%d) = %v, want non-negative", a, d)
+				}
+				if tt.max > 0 && d > tt.max {
+					t.Errorf("Delay(%d) = %v, want at most %v", a, d, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestDelayGrowsToLimit(t *testing.T) {
+	// without a max, unbounded policies saturate instead of wrapping around
+	tests := []struct {
+		name string
+		p    RetryPolicy
+		min  time.Duration // lower bound at attempt 1000
+	}{
+		{"linear", Linear{Base: time.Hour * 24 * 365 * 100}, math.MaxInt64},
+		{"exponential", Exponential{Base: time.Second}, math.MaxInt64 * 8 / 10},
+		{"fibonacci", Fibonacci{Base: time.Second}, math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if d := tt.p.Delay(1000); d < tt.min {
+				t.Errorf("Delay(1000) = %v, want at least %v", d, tt.min)
+			}
+		})
+	}
+}
+
+func TestExponentialJitter(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{1: time.Second, 4: 8 * time.Second, 20: time.Minute} {
+		for range 100 {
+			d := ExponentialJitter(time.Second, time.Minute, attempt)
+			if d < want*8/10 || d > want*12/10 {
+				t.Fatalf("ExponentialJitter(1s, 1m, %d) = %v, want %v +/- 20%%", attempt, d, want)
+			}
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name   string
+		lo, hi time.Duration
+	}{
+		{"empty", time.Second, time.Second},
+		{"reversed", time.Second, 0},
+		{"small", 0, 10},
+		{"span beyond MaxInt64", -math.MaxInt64, math.MaxInt64},
+		{"top of range", math.MaxInt64 - 10, math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 100 {
+				d := between(tt.lo, tt.hi)
+				if tt.hi <= tt.lo {
+					if d != tt.lo {
+						t.Fatalf("between(%v, %v) = %v, want lo", tt.lo, tt.hi, d)
+					}
+					continue
+				}
+				if d < tt.lo || d >= tt.hi {
+					t.Fatalf("between(%v, %v) = %v, out of range", tt.lo, tt.hi, d)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSpecWithoutMax(t *testing.T) {
+	spec, err := ParseSpec("exponential:1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := spec.RetryPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []int{35, 100, 1000} {
+		if d := p.Delay(a); d < 0 {
+			t.Errorf("Delay(%d) = %v", a, d)
+		}
+	}
+}
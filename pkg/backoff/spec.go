@@ -0,0 +1,75 @@
+package backoff
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Policy names accepted by Spec.
+const (
+	PolicyConstant     = "constant"
+	PolicyLinear       = "linear"
+	PolicyExponential  = "exponential"
+	PolicyDecorrelated = "decorrelated"
+	PolicyFibonacci    = "fibonacci"
+)
+
+// Spec is the serialisable form of a RetryPolicy, used to pick a policy per
+// task at enqueue time and per task type in worker flags.
+type Spec struct {
+	Policy string `json:"policy"`
+	BaseMs int64  `json:"base_ms"`
+	MaxMs  int64  `json:"max_ms,omitempty"`
+}
+
+// RetryPolicy builds the policy described by s.
+func (s Spec) RetryPolicy() (RetryPolicy, error) {
+	base := time.Duration(s.BaseMs) * time.Millisecond
+	max := time.Duration(s.MaxMs) * time.Millisecond
+	if base <= 0 {
+		return nil, fmt.Errorf("retry policy %q needs a positive base", s.Policy)
+	}
+
+	switch s.Policy {
+	case PolicyConstant:
+		return Constant{Interval: base}, nil
+	case PolicyLinear:
+		return Linear{Base: base, Max: max}, nil
+	case PolicyExponential:
+		return Exponential{Base: base, Max: max}, nil
+	case PolicyDecorrelated:
+		return DecorrelatedJitter{Base: base, Max: max}, nil
+	case PolicyFibonacci:
+		return Fibonacci{Base: base, Max: max}, nil
+	default:
+		return nil, fmt.Errorf("unknown retry policy %q", s.Policy)
+	}
+}
+
+// ParseSpec parses "policy:base[:max]", e.g. "linear:1s:1m" or "constant:5s".
+func ParseSpec(s string) (Spec, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Spec{}, fmt.Errorf("invalid retry spec %q, want policy:base[:max]", s)
+	}
+
+	base, err := time.ParseDuration(parts[1])
+	if err != nil {
+		return Spec{}, fmt.Errorf("invalid retry base in %q: %w", s, err)
+	}
+	spec := Spec{Policy: parts[0], BaseMs: base.Milliseconds()}
+
+	if len(parts) == 3 {
+		max, err := time.ParseDuration(parts[2])
+		if err != nil {
+			return Spec{}, fmt.Errorf("invalid retry max in %q: %w", s, err)
+		}
+		spec.MaxMs = max.Milliseconds()
+	}
+
+	if _, err := spec.RetryPolicy(); err != nil {
+		return Spec{}, err
+	}
+	return spec, nil
+}